@@ -0,0 +1,71 @@
+package main
+
+import (
+	"log"
+
+	"github.com/cosmos/cosmos-sdk/server"
+	servertypes "github.com/cosmos/cosmos-sdk/server/types"
+	"github.com/spf13/viper"
+	"github.com/terra-money/mantlemint/config"
+)
+
+// app.toml keys read by wasmd
+const (
+	appOptWasmMemoryCacheSize    = "wasm.memory_cache_size"
+	appOptWasmQueryGasLimit      = "wasm.query_gas_limit"
+	appOptWasmSimulationGasLimit = "wasm.simulation_gas_limit"
+)
+
+// legacyWasmKeys maps [wasm] keys of terra core < v2, still found in many app.toml files,
+// to the keys read by wasmd
+var legacyWasmKeys = map[string]string{
+	"wasm.contract-memory-cache-size": appOptWasmMemoryCacheSize,
+	"wasm.contract-query-gas-limit":   appOptWasmQueryGasLimit,
+	"wasm.contract-debug-mode":        server.FlagTrace,
+}
+
+var _ servertypes.AppOptions = (*appOptions)(nil)
+
+// appOptions serves app.toml (merged into viper) to the app,
+// with mantlemint settings taking precedence
+type appOptions struct {
+	vpr       *viper.Viper
+	overrides map[string]interface{}
+}
+
+func (opts *appOptions) Get(key string) interface{} {
+	if value, ok := opts.overrides[key]; ok {
+		return value
+	}
+	return opts.vpr.Get(key)
+}
+
+func newAppOptions(mantlemintConfig config.Config, vpr *viper.Viper) *appOptions {
+	opts := &appOptions{
+		vpr:       vpr,
+		overrides: make(map[string]interface{}),
+	}
+
+	for legacyKey, key := range legacyWasmKeys {
+		if vpr.IsSet(legacyKey) && !vpr.IsSet(key) {
+			log.Printf("[app] app.toml: %s is deprecated, use %s instead", legacyKey, key)
+			opts.overrides[key] = vpr.Get(legacyKey)
+		}
+	}
+
+	// [wasm] of mantlemint config; zero values leave app.toml as is
+	if mantlemintConfig.WasmMemoryCacheSize > 0 {
+		opts.overrides[appOptWasmMemoryCacheSize] = mantlemintConfig.WasmMemoryCacheSize
+	}
+	if mantlemintConfig.WasmQueryGasLimit > 0 {
+		opts.overrides[appOptWasmQueryGasLimit] = mantlemintConfig.WasmQueryGasLimit
+	}
+	if mantlemintConfig.WasmSimulationGasLimit > 0 {
+		opts.overrides[appOptWasmSimulationGasLimit] = mantlemintConfig.WasmSimulationGasLimit
+	}
+	if mantlemintConfig.WasmContractDebugMode {
+		opts.overrides[server.FlagTrace] = true
+	}
+
+	return opts
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/CosmWasm/wasmd/x/wasm"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/terra-money/mantlemint/config"
+)
+
+func TestAppOptions(t *testing.T) {
+	vpr := viper.New()
+	vpr.Set("wasm.contract-memory-cache-size", "2048")
+	vpr.Set("wasm.contract-query-gas-limit", "3000000")
+	vpr.Set("wasm.contract-debug-mode", "true")
+	vpr.Set("wasm.simulation_gas_limit", "50000000")
+
+	// legacy terra keys are translated
+	wasmConfig, err := wasm.ReadWasmConfig(newAppOptions(config.Config{}, vpr))
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2048), wasmConfig.MemoryCacheSize)
+	assert.Equal(t, uint64(3000000), wasmConfig.SmartQueryGasLimit)
+	assert.Equal(t, uint64(50000000), *wasmConfig.SimulationGasLimit)
+	assert.True(t, wasmConfig.ContractDebugMode)
+
+	// mantlemint config takes precedence over app.toml
+	wasmConfig, err = wasm.ReadWasmConfig(newAppOptions(config.Config{
+		WasmMemoryCacheSize: 4096,
+		WasmQueryGasLimit:   1000000,
+	}, vpr))
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(4096), wasmConfig.MemoryCacheSize)
+	assert.Equal(t, uint64(1000000), wasmConfig.SmartQueryGasLimit)
+}
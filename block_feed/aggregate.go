@@ -1,31 +1,53 @@
 package block_feed
 
 import (
+	"bytes"
 	"fmt"
 	"log"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-var _ BlockFeed = (*AggregateSubscription)(nil)
+var (
+	_ SyncAwareBlockFeed = (*AggregateSubscription)(nil)
+	_ HaltingBlockFeed   = (*AggregateSubscription)(nil)
+	_ StatusBlockFeed    = (*AggregateSubscription)(nil)
+)
 
 type AggregateSubscription struct {
 	ws                    *WSSubscription
 	rpc                   *RPCSubscription
-	lastKnownBlock        int64
+	lastKnownBlock        atomic.Int64 // read by Status while the feed runs
+	lastKnownHash         []byte
 	lastKnownEndpointIdx  int
 	aggregateBlockChannel chan *BlockResult
-	wsEndpointsLength     int
-	isSynced              bool
+	wsHealth              *HealthTracker
+	haltChannel           chan error
+	isSynced              atomic.Bool
+	isClosed              atomic.Bool
+
+	// stall detection; a stall closes the websocket, which triggers failover
+	stallTimeout        time.Duration
+	stallDetectorOnce   sync.Once
+	lastBlockMtx        sync.RWMutex
+	lastBlockReceivedAt time.Time
 }
 
 var done *BlockResult = nil
 
+// NewAggregateBlockFeed creates a feed continuing from currentBlock, the hash of which is currentBlockHash;
+// the first block fed must point at it
 func NewAggregateBlockFeed(
 	currentBlock int64,
+	currentBlockHash []byte,
 	rpcEndpoints []string,
 	wsEndpoints []string,
+	rpcSyncWindow int,
+	wsPingInterval time.Duration,
+	stallTimeout time.Duration,
 ) *AggregateSubscription {
-	rpc, rpcErr := NewRpcSubscription(rpcEndpoints)
+	rpc, rpcErr := NewRpcSubscription(rpcEndpoints, rpcSyncWindow)
 	if rpcErr != nil {
 		panic(rpcErr)
 	}
@@ -35,16 +57,20 @@ func NewAggregateBlockFeed(
 	if wsErr != nil {
 		panic(wsErr)
 	}
+	ws.SetPingInterval(wsPingInterval)
 
-	return &AggregateSubscription{
+	ags := &AggregateSubscription{
 		ws:                    ws,
 		rpc:                   rpc,
-		lastKnownBlock:        currentBlock,
+		lastKnownHash:         currentBlockHash,
 		lastKnownEndpointIdx:  0,
 		aggregateBlockChannel: make(chan *BlockResult),
-		wsEndpointsLength:     len(wsEndpoints),
-		isSynced:              false,
+		wsHealth:              NewHealthTracker(wsEndpoints),
+		haltChannel:           make(chan error, 1),
+		stallTimeout:          stallTimeout,
 	}
+	ags.lastKnownBlock.Store(currentBlock)
+	return ags
 }
 
 func (ags *AggregateSubscription) Subscribe(rpcIndex int) (chan *BlockResult, error) {
@@ -55,92 +81,233 @@ func (ags *AggregateSubscription) Subscribe(rpcIndex int) (chan *BlockResult, er
 	}
 
 	// create websocket subscriber
+	dialStart := time.Now()
 	cWS, cWSErr := ags.ws.Subscribe(rpcIndex)
 	if cWSErr != nil {
+		ags.wsHealth.RecordFailure(rpcIndex, cWSErr)
 		return nil, cWSErr
 	}
+	ags.wsHealth.RecordSuccess(rpcIndex, time.Since(dialStart), 0)
+	ags.lastKnownEndpointIdx = rpcIndex
 
 	// start with isSynced flag false
 	ags.setSyncState(false)
 
-	// read the first cWS
-	<-cWS
-
-	// check if the first block received from ws is the right block (currentHeight + 1)
-	// if not, the local blockchain is behind, in such case we would need to sync from Rpc.
-	if firstBlock := <-cWS; firstBlock.Block.Header.Height != ags.lastKnownBlock+1 {
-		log.Printf("[block_feed/aggregate] received the first block(%d), but local blockchain is at (%d)\n", firstBlock.Block.Header.Height, ags.lastKnownBlock)
-		go func() {
-			go ags.rpc.SyncFromUntil(ags.lastKnownBlock+1, firstBlock.Block.Header.Height, rpcIndex)
-			for {
-				r := <-cRpc
-				if r != done {
-					ags.aggregateBlockChannel <- r
-					ags.lastKnownBlock = r.Block.Height
-				} else {
-					break
+	// a fresh connection gets a full stall timeout to deliver its first block
+	ags.markBlockReceived()
+	ags.stallDetectorOnce.Do(func() {
+		if ags.stallTimeout > 0 {
+			go ags.detectStall()
+		}
+	})
+
+	go func() {
+		for {
+			r := <-cWS
+
+			// gracefully handle done signal; in whatever case received is nil,
+			// handle reconnection here
+			if r == done {
+				ags.setSyncState(false)
+				ags.wsHealth.RecordFailure(rpcIndex, fmt.Errorf("websocket disconnected"))
+				if ags.isClosed.Load() {
+					log.Printf("[block_feed/aggregate] websocket closed")
+					return
 				}
+				log.Printf("[block_feed/aggregate] websocket done signal received, reconnecting...")
+				ags.closeSubscriptions()
+				ags.Reconnect()
+				return
 			}
+			ags.wsHealth.RecordHeight(rpcIndex, r.Block.Height)
 
-			log.Printf("[block_feed/aggregate] switching to ws...")
-
-			// patch ws to aggregate
-			for {
-				r := <-cWS
-
-				// gracefully handle done signal; in whatever case received is nil,
-				// handle reconnection here
-				if r == done {
-					log.Printf("[block_feed/aggregate] websocket done signal received, reconnecting...")
-					ags.setSyncState(false)
-					ags.Close()
-					ags.Reconnect()
-					break
-				} else {
-					// if block feeder got upto this point,
-					// it is relatively safe that mantle is synced
-					ags.setSyncState(true)
-					ags.aggregateBlockChannel <- r
-					ags.lastKnownBlock = r.Block.Height
+			// websocket is ahead of the local blockchain, either because mantlemint is catching up
+			// or because the websocket skipped some heights; backfill from rpc.
+			if lastKnownBlock := ags.lastKnownBlock.Load(); r.Block.Height > lastKnownBlock+1 {
+				log.Printf("[block_feed/aggregate] received block(%d), but local blockchain is at (%d); syncing from rpc\n", r.Block.Height, lastKnownBlock)
+				syncErr := make(chan error, 1)
+				go func(from, to int64) {
+					syncErr <- ags.rpc.SyncFromUntil(from, to, rpcIndex)
+				}(lastKnownBlock+1, r.Block.Height-1)
+			backfill:
+				for {
+					select {
+					case b := <-cRpc:
+						if b == done {
+							break backfill
+						}
+						if err := ags.emit(b); err != nil {
+							ags.halt(err)
+							return
+						}
+					case err := <-syncErr:
+						// done is delivered before a completed sync returns; this one stopped early
+						log.Printf("[block_feed/aggregate] backfill stopped, %v", err)
+						return
+					}
 				}
+				log.Printf("[block_feed/aggregate] switching to ws...")
 			}
-		}()
-	}
+
+			if err := ags.emit(r); err != nil {
+				ags.halt(err)
+				return
+			}
+
+			// if block feeder got upto this point,
+			// it is relatively safe that mantle is synced
+			ags.setSyncState(true)
+		}
+	}()
 
 	return ags.aggregateBlockChannel, nil
 }
 
+// emit sends a block to the aggregate channel, making sure blocks form a chain:
+// blocks at or below the last known height are dropped as duplicates,
+// and a block not pointing at the last emitted block is a fork.
+func (ags *AggregateSubscription) emit(r *BlockResult) error {
+	height := r.Block.Height
+	lastKnownBlock := ags.lastKnownBlock.Load()
+
+	if height <= lastKnownBlock {
+		if height == lastKnownBlock && len(ags.lastKnownHash) > 0 && !bytes.Equal(r.Block.Hash(), ags.lastKnownHash) {
+			return &ForkError{Height: height, Expected: ags.lastKnownHash, Actual: r.Block.Hash()}
+		}
+		log.Printf("[block_feed/aggregate] dropping duplicate block %d", height)
+		return nil
+	}
+
+	if height != lastKnownBlock+1 {
+		return fmt.Errorf("block %d received out of order, expected %d", height, lastKnownBlock+1)
+	}
+
+	if len(ags.lastKnownHash) > 0 && !bytes.Equal(r.Block.LastBlockID.Hash, ags.lastKnownHash) {
+		return &ForkError{Height: height - 1, Expected: ags.lastKnownHash, Actual: r.Block.LastBlockID.Hash}
+	}
+
+	ags.aggregateBlockChannel <- r
+	ags.lastKnownBlock.Store(height)
+	ags.lastKnownHash = r.Block.Hash()
+	ags.markBlockReceived()
+
+	return nil
+}
+
+func (ags *AggregateSubscription) markBlockReceived() {
+	ags.lastBlockMtx.Lock()
+	ags.lastBlockReceivedAt = time.Now()
+	ags.lastBlockMtx.Unlock()
+}
+
+// LastBlockReceivedAt returns when the last block was handed out
+func (ags *AggregateSubscription) LastBlockReceivedAt() time.Time {
+	ags.lastBlockMtx.RLock()
+	defer ags.lastBlockMtx.RUnlock()
+	return ags.lastBlockReceivedAt
+}
+
+func (ags *AggregateSubscription) isStalled() bool {
+	return ags.stallTimeout > 0 && time.Since(ags.LastBlockReceivedAt()) > ags.stallTimeout
+}
+
+// detectStall closes the websocket whenever no block arrived within stallTimeout.
+// A half-open connection or an upstream node that stopped producing blocks
+// then goes through the regular reconnection path, which picks another endpoint.
+func (ags *AggregateSubscription) detectStall() {
+	ticker := time.NewTicker(ags.stallTimeout / 4)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if ags.isClosed.Load() {
+			return
+		}
+		if !ags.isStalled() {
+			continue
+		}
+
+		log.Printf("[block_feed/aggregate] no block received since %s, reconnecting...", ags.LastBlockReceivedAt().Format(time.RFC3339))
+		ags.setSyncState(false)
+		// restart the clock, so that a slow reconnection isn't detected as another stall
+		ags.markBlockReceived()
+		if err := ags.ws.Close(); err != nil {
+			log.Printf("[block_feed/aggregate] failed to close stalled websocket, %v", err)
+		}
+	}
+}
+
+// halt stops the subscription for good, and reports err through Halted
+func (ags *AggregateSubscription) halt(err error) {
+	log.Printf("[block_feed/aggregate] !!! halting block feed: %v", err)
+	ags.setSyncState(false)
+	_ = ags.Close()
+	ags.haltChannel <- err
+}
+
+// Halted delivers an error when the feed stops due to an inconsistent block stream
+func (ags *AggregateSubscription) Halted() <-chan error {
+	return ags.haltChannel
+}
+
+// Close stops the subscription for good; no reconnection is attempted afterwards.
 func (ags *AggregateSubscription) Close() error {
+	ags.isClosed.Store(true)
+	return ags.closeSubscriptions()
+}
+
+func (ags *AggregateSubscription) closeSubscriptions() error {
 	rpcCloseErr := ags.rpc.Close()
 	wsCloseErr := ags.ws.Close()
+	if rpcCloseErr == nil && wsCloseErr == nil {
+		return nil
+	}
 
-	return fmt.Errorf("error during aggregate subscription close: %s, %s", rpcCloseErr, wsCloseErr)
+	return fmt.Errorf("error during aggregate subscription close: %v, %v", rpcCloseErr, wsCloseErr)
 }
 
 // Reconnect reestablishes all underlying connections
 // On any reconnection, it is likely that the underlying RPC is having some problem.
-// To mitigate this,
+// To mitigate this, the healthiest websocket endpoint other than the last one is picked,
+// backing off exponentially while every endpoint keeps failing.
 func (ags *AggregateSubscription) Reconnect() {
-	endpointIndex := ags.nextWSEndpoint()
-	time.Sleep(time.Second)
+	for attempt := 0; !ags.isClosed.Load(); attempt++ {
+		endpointIndex := ags.wsHealth.Best(ags.lastKnownEndpointIdx)
+		wait := backoff(attempt)
+		time.Sleep(wait)
 
-	log.Printf("[block_feed/aggregate] reconnecting with rpcIndex of %d\n", endpointIndex)
-	if _, err := ags.Subscribe(endpointIndex); err != nil {
-		ags.Reconnect()
+		log.Printf("[block_feed/aggregate] reconnecting to %s (attempt %d)\n", ags.wsHealth.URL(endpointIndex), attempt+1)
+		if _, err := ags.Subscribe(endpointIndex); err != nil {
+			log.Printf("[block_feed/aggregate] reconnection failed, %v", err)
+			ags.lastKnownEndpointIdx = endpointIndex
+			continue
+		}
+		return
 	}
 }
 
-func (ags *AggregateSubscription) IsSynced() bool {
-	return ags.isSynced
+// Health returns health of rpc and websocket endpoints
+func (ags *AggregateSubscription) Health() map[string][]EndpointHealth {
+	return map[string][]EndpointHealth{
+		"rpc": ags.rpc.Health(),
+		"ws":  ags.wsHealth.Snapshot(),
+	}
 }
 
-func (ags *AggregateSubscription) setSyncState(state bool) {
-	ags.isSynced = state
+func (ags *AggregateSubscription) IsSynced() bool {
+	return ags.isSynced.Load() && !ags.isStalled()
 }
 
-func (ags *AggregateSubscription) nextWSEndpoint() int {
-	ags.lastKnownEndpointIdx++
-	ags.lastKnownEndpointIdx = ags.lastKnownEndpointIdx % ags.wsEndpointsLength
+// Status reports sync state, the last block and health of all endpoints
+func (ags *AggregateSubscription) Status() FeedStatus {
+	return FeedStatus{
+		Synced:              ags.IsSynced(),
+		Stalled:             ags.isStalled(),
+		LastHeight:          ags.lastKnownBlock.Load(),
+		LastBlockReceivedAt: ags.LastBlockReceivedAt(),
+		Endpoints:           ags.Health(),
+	}
+}
 
-	return ags.lastKnownEndpointIdx
+func (ags *AggregateSubscription) setSyncState(state bool) {
+	ags.isSynced.Store(state)
 }
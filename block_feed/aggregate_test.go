@@ -0,0 +1,91 @@
+package block_feed
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	tendermint "github.com/cometbft/cometbft/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregateEmit(t *testing.T) {
+	ags := &AggregateSubscription{
+		aggregateBlockChannel: make(chan *BlockResult, 10),
+	}
+	ags.lastKnownBlock.Store(9)
+
+	newBlock := func(height int64, lastBlockHash []byte, chainID string) *BlockResult {
+		return &BlockResult{Block: &tendermint.Block{LastCommit: &tendermint.Commit{}, Header: tendermint.Header{
+			ChainID:        chainID,
+			Height:         height,
+			LastBlockID:    tendermint.BlockID{Hash: lastBlockHash},
+			Time:           time.Unix(1234, 0),
+			ValidatorsHash: []byte("validators"),
+		}}}
+	}
+
+	b10 := newBlock(10, nil, "columbus-5")
+	assert.Nil(t, ags.emit(b10))
+	b11 := newBlock(11, b10.Block.Hash(), "columbus-5")
+	assert.Nil(t, ags.emit(b11))
+	assert.Len(t, ags.aggregateBlockChannel, 2)
+
+	// duplicate is dropped
+	assert.Nil(t, ags.emit(b11))
+	assert.Len(t, ags.aggregateBlockChannel, 2)
+
+	// same height, different block
+	var forkErr *ForkError
+	assert.True(t, errors.As(ags.emit(newBlock(11, b10.Block.Hash(), "other")), &forkErr))
+
+	// next block not pointing at the last one
+	assert.True(t, errors.As(ags.emit(newBlock(12, b10.Block.Hash(), "columbus-5")), &forkErr))
+
+	// gaps must be backfilled before emitting
+	assert.NotNil(t, ags.emit(newBlock(13, nil, "columbus-5")))
+
+	assert.Nil(t, ags.emit(newBlock(12, b11.Block.Hash(), "columbus-5")))
+	assert.Equal(t, int64(12), ags.lastKnownBlock.Load())
+}
+
+func TestAggregateEmitAfterRestart(t *testing.T) {
+	lastHash := []byte("last injected block")
+	ags := &AggregateSubscription{
+		lastKnownHash:         lastHash,
+		aggregateBlockChannel: make(chan *BlockResult, 10),
+	}
+	ags.lastKnownBlock.Store(9)
+
+	newBlock := func(lastBlockHash []byte) *BlockResult {
+		return &BlockResult{Block: &tendermint.Block{LastCommit: &tendermint.Commit{}, Header: tendermint.Header{
+			ChainID:        "columbus-5",
+			Height:         10,
+			LastBlockID:    tendermint.BlockID{Hash: lastBlockHash},
+			ValidatorsHash: []byte("validators"),
+		}}}
+	}
+
+	var forkErr *ForkError
+	assert.True(t, errors.As(ags.emit(newBlock([]byte("another block"))), &forkErr))
+	assert.Equal(t, int64(9), forkErr.Height)
+	assert.Nil(t, ags.emit(newBlock(lastHash)))
+}
+
+func TestAggregateStall(t *testing.T) {
+	ags := &AggregateSubscription{
+		stallTimeout: time.Minute,
+	}
+
+	ags.setSyncState(true)
+	ags.markBlockReceived()
+	assert.True(t, ags.IsSynced())
+
+	ags.lastBlockReceivedAt = time.Now().Add(-2 * time.Minute)
+	assert.False(t, ags.IsSynced())
+	assert.True(t, ags.isStalled())
+
+	// disabled
+	ags.stallTimeout = 0
+	assert.True(t, ags.IsSynced())
+}
@@ -0,0 +1,341 @@
+package block_feed
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"sync"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	tmjson "github.com/cometbft/cometbft/libs/json"
+	tendermint "github.com/cometbft/cometbft/types"
+	"github.com/golang/snappy"
+)
+
+// Block archive is a flat file of blocks in height order:
+//
+//	header: magic "MMBA" | version (1 byte) | from (int64 BE) | to (int64 BE)
+//	record: payload length (uint32 BE) | crc32 of payload (uint32 BE) | payload
+//
+// where payload is a snappy compressed, amino-json encoded ArchiveRecord.
+const (
+	archiveVersion byte = 1
+
+	// guards against allocating absurd buffers for corrupted length fields
+	archiveMaxRecordSize = 256 << 20
+
+	// results and commits of this many replayed blocks are kept for BlockResults and FetchCommit
+	archiveResultsKept = 1024
+)
+
+var (
+	archiveMagic = []byte("MMBA")
+
+	ErrArchiveCorrupted = errors.New("block archive is corrupted")
+)
+
+// ArchiveRecord is a single block in an archive, along with the commit signing it; Results is optional
+type ArchiveRecord struct {
+	BlockID *tendermint.BlockID      `json:"block_id"`
+	Block   *tendermint.Block        `json:"block"`
+	Commit  *tendermint.Commit       `json:"commit,omitempty"`
+	Results []abci.ResponseDeliverTx `json:"results,omitempty"`
+}
+
+type ArchiveWriter struct {
+	w        *bufio.Writer
+	from     int64
+	to       int64
+	expected int64
+}
+
+func NewArchiveWriter(w io.Writer, from, to int64) (*ArchiveWriter, error) {
+	bw := bufio.NewWriter(w)
+
+	header := make([]byte, 0, len(archiveMagic)+1+16)
+	header = append(header, archiveMagic...)
+	header = append(header, archiveVersion)
+	header = binary.BigEndian.AppendUint64(header, uint64(from))
+	header = binary.BigEndian.AppendUint64(header, uint64(to))
+	if _, err := bw.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &ArchiveWriter{w: bw, from: from, to: to, expected: from}, nil
+}
+
+// Write appends a record; records must be written in height order without gaps
+func (aw *ArchiveWriter) Write(record *ArchiveRecord) error {
+	if record.Block.Height != aw.expected {
+		return fmt.Errorf("expected block %d, got %d", aw.expected, record.Block.Height)
+	}
+
+	recordJSON, err := tmjson.Marshal(record)
+	if err != nil {
+		return err
+	}
+	payload := snappy.Encode(nil, recordJSON)
+
+	prefix := make([]byte, 8)
+	binary.BigEndian.PutUint32(prefix[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(prefix[4:], crc32.ChecksumIEEE(payload))
+	if _, err := aw.w.Write(prefix); err != nil {
+		return err
+	}
+	if _, err := aw.w.Write(payload); err != nil {
+		return err
+	}
+
+	aw.expected++
+	return nil
+}
+
+// Close flushes buffered records; it fails if the archive doesn't cover the whole range
+func (aw *ArchiveWriter) Close() error {
+	if err := aw.w.Flush(); err != nil {
+		return err
+	}
+	if aw.expected != aw.to+1 {
+		return fmt.Errorf("archive is incomplete; wrote blocks %d..%d of %d..%d", aw.from, aw.expected-1, aw.from, aw.to)
+	}
+	return nil
+}
+
+type ArchiveReader struct {
+	r        *bufio.Reader
+	From     int64
+	To       int64
+	expected int64
+}
+
+func NewArchiveReader(r io.Reader) (*ArchiveReader, error) {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, len(archiveMagic)+1+16)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("failed to read archive header: %w", err)
+	}
+	if !bytes.Equal(header[:len(archiveMagic)], archiveMagic) {
+		return nil, fmt.Errorf("not a block archive")
+	}
+	if version := header[len(archiveMagic)]; version != archiveVersion {
+		return nil, fmt.Errorf("unsupported block archive version %d", version)
+	}
+
+	from := int64(binary.BigEndian.Uint64(header[len(archiveMagic)+1:]))
+	to := int64(binary.BigEndian.Uint64(header[len(archiveMagic)+9:]))
+
+	return &ArchiveReader{r: br, From: from, To: to, expected: from}, nil
+}
+
+// Next returns the next record, or io.EOF after the last one
+func (ar *ArchiveReader) Next() (*ArchiveRecord, error) {
+	prefix := make([]byte, 8)
+	if _, err := io.ReadFull(ar.r, prefix); err == io.EOF {
+		if ar.expected != ar.To+1 {
+			return nil, fmt.Errorf("%w; archive ends at block %d, expected %d", ErrArchiveCorrupted, ar.expected-1, ar.To)
+		}
+		return nil, io.EOF
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrArchiveCorrupted, err)
+	}
+
+	length := binary.BigEndian.Uint32(prefix[:4])
+	checksum := binary.BigEndian.Uint32(prefix[4:])
+	if length > archiveMaxRecordSize {
+		return nil, fmt.Errorf("%w; record of %d bytes at block %d", ErrArchiveCorrupted, length, ar.expected)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(ar.r, payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrArchiveCorrupted, err)
+	}
+	if crc32.ChecksumIEEE(payload) != checksum {
+		return nil, fmt.Errorf("%w; checksum mismatch at block %d", ErrArchiveCorrupted, ar.expected)
+	}
+
+	recordJSON, err := snappy.Decode(nil, payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrArchiveCorrupted, err)
+	}
+
+	record := new(ArchiveRecord)
+	if err := tmjson.Unmarshal(recordJSON, record); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrArchiveCorrupted, err)
+	}
+	if record.Block == nil || record.Block.Height != ar.expected {
+		return nil, fmt.Errorf("%w; expected block %d", ErrArchiveCorrupted, ar.expected)
+	}
+
+	ar.expected++
+	return record, nil
+}
+
+var (
+	_ SyncAwareBlockFeed = (*ArchiveSubscription)(nil)
+	_ BlockFetcher       = (*ArchiveSubscription)(nil)
+)
+
+// ArchiveSubscription replays blocks from a block archive file, then closes the channel
+type ArchiveSubscription struct {
+	file           *os.File
+	reader         *ArchiveReader
+	lastKnownBlock int64
+	c              chan *BlockResult
+	isSynced       bool
+	isClosed       bool
+	mtx            *sync.Mutex
+
+	// results and commits of recently replayed blocks; blocks without txs have no results to keep
+	results map[int64][]abci.ResponseDeliverTx
+	commits map[int64]*tendermint.Commit
+}
+
+func NewArchiveSubscription(currentBlock int64, path string) (*ArchiveSubscription, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := NewArchiveReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if reader.From > currentBlock+1 {
+		file.Close()
+		return nil, fmt.Errorf("archive starts at height %d, but mantlemint needs block %d", reader.From, currentBlock+1)
+	}
+
+	return &ArchiveSubscription{
+		file:           file,
+		reader:         reader,
+		lastKnownBlock: currentBlock,
+		c:              make(chan *BlockResult),
+		mtx:            new(sync.Mutex),
+		results:        make(map[int64][]abci.ResponseDeliverTx),
+		commits:        make(map[int64]*tendermint.Commit),
+	}, nil
+}
+
+// Subscribe replays every block after lastKnownBlock; rpcIndex is ignored.
+func (as *ArchiveSubscription) Subscribe(_ int) (chan *BlockResult, error) {
+	log.Printf("[block_feed/archive] replaying blocks from=%d, to=%d\n", as.lastKnownBlock+1, as.reader.To)
+
+	go func() {
+		defer close(as.c)
+		for {
+			as.mtx.Lock()
+			if as.isClosed {
+				as.mtx.Unlock()
+				return
+			}
+			record, err := as.reader.Next()
+			as.mtx.Unlock()
+
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				log.Printf("[block_feed/archive] stopping replay, %v", err)
+				return
+			}
+
+			// archive may start before the current height
+			if record.Block.Height <= as.lastKnownBlock {
+				continue
+			}
+
+			height := record.Block.Height
+			as.mtx.Lock()
+			if len(record.Results) > 0 || len(record.Block.Txs) == 0 {
+				as.results[height] = record.Results
+			}
+			delete(as.results, height-archiveResultsKept)
+			if record.Commit != nil {
+				as.commits[height] = record.Commit
+			}
+			delete(as.commits, height-archiveResultsKept)
+			as.mtx.Unlock()
+
+			as.c <- &BlockResult{BlockID: record.BlockID, Block: record.Block, Results: record.Results}
+			as.lastKnownBlock = height
+		}
+
+		log.Printf("[block_feed/archive] reached the end of archive at height %d", as.lastKnownBlock)
+		as.mtx.Lock()
+		as.isSynced = true
+		as.mtx.Unlock()
+	}()
+
+	return as.c, nil
+}
+
+// FetchBlock reads ahead to the block at height. The archive is only read forward,
+// so heights must be fetched in order, and before Subscribe replays past them.
+func (as *ArchiveSubscription) FetchBlock(height int64) (*BlockResult, error) {
+	as.mtx.Lock()
+	defer as.mtx.Unlock()
+	if as.isClosed {
+		return nil, fmt.Errorf("archive is closed")
+	}
+	if height < as.reader.expected || height > as.reader.To {
+		return nil, fmt.Errorf("block %d can't be read from the archive; next block is %d of %d..%d", height, as.reader.expected, as.reader.From, as.reader.To)
+	}
+
+	for {
+		record, err := as.reader.Next()
+		if err != nil {
+			return nil, err
+		}
+		if record.Block.Height == height {
+			return &BlockResult{BlockID: record.BlockID, Block: record.Block, Results: record.Results}, nil
+		}
+	}
+}
+
+// FetchCommit returns the commit archived along with a replayed block
+func (as *ArchiveSubscription) FetchCommit(height int64) (*tendermint.Commit, error) {
+	as.mtx.Lock()
+	defer as.mtx.Unlock()
+	commit, ok := as.commits[height]
+	if !ok {
+		return nil, fmt.Errorf("block %d has no commit in the archive", height)
+	}
+	delete(as.commits, height)
+	return commit, nil
+}
+
+// BlockResults returns DeliverTx results archived along with a replayed block,
+// for the results verifier; archives exported without results have none
+func (as *ArchiveSubscription) BlockResults(height int64) ([]abci.ResponseDeliverTx, error) {
+	as.mtx.Lock()
+	defer as.mtx.Unlock()
+	results, ok := as.results[height]
+	if !ok {
+		return nil, fmt.Errorf("block %d has no results in the archive", height)
+	}
+	delete(as.results, height)
+	return results, nil
+}
+
+func (as *ArchiveSubscription) IsSynced() bool {
+	as.mtx.Lock()
+	defer as.mtx.Unlock()
+	return as.isSynced
+}
+
+func (as *ArchiveSubscription) Close() error {
+	as.mtx.Lock()
+	defer as.mtx.Unlock()
+	if as.isClosed {
+		return nil
+	}
+	as.isClosed = true
+	return as.file.Close()
+}
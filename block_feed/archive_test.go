@@ -0,0 +1,130 @@
+package block_feed
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	tmjson "github.com/cometbft/cometbft/libs/json"
+	tendermint "github.com/cometbft/cometbft/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchive(t *testing.T) {
+	block := &tendermint.Block{}
+	blockJSON, _ := os.ReadFile("../indexer/fixtures/block_4814775.json")
+	assert.Nil(t, tmjson.Unmarshal(blockJSON, block))
+
+	buf := new(bytes.Buffer)
+	writer, err := NewArchiveWriter(buf, block.Height, block.Height)
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Write(&ArchiveRecord{Block: block}))
+	assert.Nil(t, writer.Close())
+
+	archive := buf.Bytes()
+	reader, err := NewArchiveReader(bytes.NewReader(archive))
+	assert.Nil(t, err)
+	assert.Equal(t, block.Height, reader.From)
+
+	record, err := reader.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, block.Hash(), record.Block.Hash())
+
+	_, err = reader.Next()
+	assert.Equal(t, io.EOF, err)
+
+	// flip a byte in the payload
+	archive[len(archive)-1] ^= 0xff
+	reader, _ = NewArchiveReader(bytes.NewReader(archive))
+	_, err = reader.Next()
+	assert.True(t, errors.Is(err, ErrArchiveCorrupted))
+
+	// truncated archive
+	reader, _ = NewArchiveReader(bytes.NewReader(archive[:21]))
+	_, err = reader.Next()
+	assert.True(t, errors.Is(err, ErrArchiveCorrupted))
+}
+
+func TestArchiveReplayResults(t *testing.T) {
+	newBlock := func(height int64, txs ...string) *tendermint.Block {
+		blockTxs := make([]tendermint.Tx, len(txs))
+		for i, tx := range txs {
+			blockTxs[i] = tendermint.Tx(tx)
+		}
+		return tendermint.MakeBlock(height, blockTxs, &tendermint.Commit{}, nil)
+	}
+
+	path := filepath.Join(t.TempDir(), "blocks.mmba")
+	file, err := os.Create(path)
+	assert.Nil(t, err)
+	writer, err := NewArchiveWriter(file, 1, 3)
+	assert.Nil(t, err)
+	// a block with txs, an empty one, and a block with txs exported without results
+	assert.Nil(t, writer.Write(&ArchiveRecord{Block: newBlock(1, "tx"), Commit: &tendermint.Commit{Height: 1}, Results: []abci.ResponseDeliverTx{{Code: 5}}}))
+	assert.Nil(t, writer.Write(&ArchiveRecord{Block: newBlock(2)}))
+	assert.Nil(t, writer.Write(&ArchiveRecord{Block: newBlock(3, "tx")}))
+	assert.Nil(t, writer.Close())
+	assert.Nil(t, file.Close())
+
+	as, err := NewArchiveSubscription(0, path)
+	assert.Nil(t, err)
+	defer as.Close()
+	c, err := as.Subscribe(0)
+	assert.Nil(t, err)
+	for block := range c {
+		if block.Block.Height == 1 {
+			assert.Equal(t, uint32(5), block.Results[0].Code)
+		}
+	}
+
+	results, err := as.BlockResults(1)
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, uint32(5), results[0].Code)
+	results, err = as.BlockResults(2)
+	assert.Nil(t, err)
+	assert.Empty(t, results)
+	_, err = as.BlockResults(3)
+	assert.ErrorContains(t, err, "no results")
+
+	commit, err := as.FetchCommit(1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), commit.Height)
+	_, err = as.FetchCommit(2)
+	assert.ErrorContains(t, err, "no commit")
+}
+
+func TestArchiveFetchBlock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocks.mmba")
+	file, err := os.Create(path)
+	assert.Nil(t, err)
+	writer, err := NewArchiveWriter(file, 1, 3)
+	assert.Nil(t, err)
+	for height := int64(1); height <= 3; height++ {
+		assert.Nil(t, writer.Write(&ArchiveRecord{Block: tendermint.MakeBlock(height, nil, &tendermint.Commit{}, nil)}))
+	}
+	assert.Nil(t, writer.Close())
+	assert.Nil(t, file.Close())
+
+	as, err := NewArchiveSubscription(3, path)
+	assert.Nil(t, err)
+	defer as.Close()
+
+	block, err := as.FetchBlock(2)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), block.Block.Height)
+
+	// the archive is only read forward
+	_, err = as.FetchBlock(1)
+	assert.ErrorContains(t, err, "can't be read")
+	_, err = as.FetchBlock(4)
+	assert.ErrorContains(t, err, "can't be read")
+
+	block, err = as.FetchBlock(3)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), block.Block.Height)
+}
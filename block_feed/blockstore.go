@@ -0,0 +1,141 @@
+package block_feed
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	dbm "github.com/cometbft/cometbft-db"
+	"github.com/cometbft/cometbft/store"
+	tendermint "github.com/cometbft/cometbft/types"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+var (
+	_ SyncAwareBlockFeed = (*BlockStoreSubscription)(nil)
+	_ BlockFetcher       = (*BlockStoreSubscription)(nil)
+)
+
+// BlockStoreSubscription reads blocks straight from the blockstore.db of a CometBFT node,
+// allowing mantlemint to replay the chain at disk speed without any network traffic.
+// The blockstore is opened read-only; the node owning it should be stopped.
+type BlockStoreSubscription struct {
+	db             dbm.DB
+	blockStore     *store.BlockStore
+	lastKnownBlock int64
+	c              chan *BlockResult
+	isSynced       bool
+	isClosed       bool
+	mtx            *sync.Mutex
+}
+
+// NewBlockStoreSubscription opens blockstore.db under dir, i.e. $TERRA_HOME/data
+func NewBlockStoreSubscription(currentBlock int64, dir string) (*BlockStoreSubscription, error) {
+	db, err := dbm.NewGoLevelDBWithOpts("blockstore", dir, &opt.Options{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+
+	blockStore := store.NewBlockStore(db)
+	if blockStore.Base() > currentBlock+1 {
+		db.Close()
+		return nil, fmt.Errorf("blockstore starts at height %d, but mantlemint needs block %d", blockStore.Base(), currentBlock+1)
+	}
+
+	return &BlockStoreSubscription{
+		db:             db,
+		blockStore:     blockStore,
+		lastKnownBlock: currentBlock,
+		c:              make(chan *BlockResult),
+		mtx:            new(sync.Mutex),
+	}, nil
+}
+
+// Subscribe feeds every block after lastKnownBlock up to the blockstore height,
+// then closes the channel. rpcIndex is ignored.
+func (bs *BlockStoreSubscription) Subscribe(_ int) (chan *BlockResult, error) {
+	to := bs.blockStore.Height()
+	log.Printf("[block_feed/blockstore] replaying blocks from=%d, to=%d\n", bs.lastKnownBlock+1, to)
+
+	go func() {
+		defer close(bs.c)
+		for height := bs.lastKnownBlock + 1; height <= to; height++ {
+			// hold the lock while reading so that Close never pulls the db out from under us
+			bs.mtx.Lock()
+			if bs.isClosed {
+				bs.mtx.Unlock()
+				return
+			}
+			block := bs.blockStore.LoadBlock(height)
+			meta := bs.blockStore.LoadBlockMeta(height)
+			bs.mtx.Unlock()
+
+			if block == nil || meta == nil {
+				log.Printf("[block_feed/blockstore] block %d not found in blockstore, stopping", height)
+				return
+			}
+
+			bs.c <- &BlockResult{
+				BlockID: &meta.BlockID,
+				Block:   block,
+			}
+			bs.lastKnownBlock = height
+		}
+
+		log.Printf("[block_feed/blockstore] reached the end of blockstore at height %d", bs.lastKnownBlock)
+		bs.mtx.Lock()
+		bs.isSynced = true
+		bs.mtx.Unlock()
+	}()
+
+	return bs.c, nil
+}
+
+// FetchBlock reads the block at height from the blockstore
+func (bs *BlockStoreSubscription) FetchBlock(height int64) (*BlockResult, error) {
+	bs.mtx.Lock()
+	defer bs.mtx.Unlock()
+	if bs.isClosed {
+		return nil, fmt.Errorf("blockstore is closed")
+	}
+
+	block := bs.blockStore.LoadBlock(height)
+	meta := bs.blockStore.LoadBlockMeta(height)
+	if block == nil || meta == nil {
+		return nil, fmt.Errorf("block %d not found in blockstore; it holds blocks %d..%d", height, bs.blockStore.Base(), bs.blockStore.Height())
+	}
+	return &BlockResult{BlockID: &meta.BlockID, Block: block}, nil
+}
+
+// FetchCommit reads the commit of the block at height from the blockstore; the last block only has a seen commit
+func (bs *BlockStoreSubscription) FetchCommit(height int64) (*tendermint.Commit, error) {
+	bs.mtx.Lock()
+	defer bs.mtx.Unlock()
+	if bs.isClosed {
+		return nil, fmt.Errorf("blockstore is closed")
+	}
+
+	if commit := bs.blockStore.LoadBlockCommit(height); commit != nil {
+		return commit, nil
+	}
+	if commit := bs.blockStore.LoadSeenCommit(height); commit != nil {
+		return commit, nil
+	}
+	return nil, fmt.Errorf("commit %d not found in blockstore", height)
+}
+
+func (bs *BlockStoreSubscription) IsSynced() bool {
+	bs.mtx.Lock()
+	defer bs.mtx.Unlock()
+	return bs.isSynced
+}
+
+func (bs *BlockStoreSubscription) Close() error {
+	bs.mtx.Lock()
+	defer bs.mtx.Unlock()
+	if bs.isClosed {
+		return nil
+	}
+	bs.isClosed = true
+	return bs.db.Close()
+}
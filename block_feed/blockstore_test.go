@@ -0,0 +1,97 @@
+package block_feed
+
+import (
+	"fmt"
+	"testing"
+
+	dbm "github.com/cometbft/cometbft-db"
+	"github.com/cometbft/cometbft/store"
+	tendermint "github.com/cometbft/cometbft/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeBlockStore writes blocks from..to into blockstore.db under dir, the way a CometBFT node would,
+// leaving out the block at missing; the blockstore then starts at from, i.e. Base() == from
+func writeBlockStore(t *testing.T, dir string, from, to, missing int64) {
+	db, err := dbm.NewGoLevelDB("blockstore", dir)
+	assert.Nil(t, err)
+	defer db.Close()
+
+	// commits without signatures; blocks only have to decode
+	commitOf := func(height int64, blockID tendermint.BlockID) *tendermint.Commit {
+		return &tendermint.Commit{Height: height, BlockID: blockID, Signatures: []tendermint.CommitSig{tendermint.NewCommitSigAbsent()}}
+	}
+	lastBlockID := tendermint.BlockID{Hash: make([]byte, 32), PartSetHeader: tendermint.PartSetHeader{Total: 1, Hash: make([]byte, 32)}}
+
+	blockStore := store.NewBlockStore(db)
+	for height := from; height <= to; height++ {
+		block := tendermint.MakeBlock(height, []tendermint.Tx{tendermint.Tx("tx")}, commitOf(height-1, lastBlockID), nil)
+		block.LastBlockID = lastBlockID
+		block.ProposerAddress = make([]byte, 20)
+		parts, err := block.MakePartSet(tendermint.BlockPartSizeBytes)
+		assert.Nil(t, err)
+		lastBlockID = tendermint.BlockID{Hash: block.Hash(), PartSetHeader: parts.Header()}
+		blockStore.SaveBlock(block, parts, commitOf(height, lastBlockID))
+	}
+
+	// a block the node never finished writing has no meta
+	assert.Nil(t, db.Delete([]byte(fmt.Sprintf("H:%d", missing))))
+}
+
+func TestBlockStoreSubscription(t *testing.T) {
+	dir := t.TempDir()
+	writeBlockStore(t, dir, 3, 6, 5)
+
+	// blocks below the base are gone
+	_, err := NewBlockStoreSubscription(0, dir)
+	assert.ErrorContains(t, err, "starts at height 3")
+
+	bs, err := NewBlockStoreSubscription(2, dir)
+	assert.Nil(t, err)
+	defer bs.Close()
+
+	block, err := bs.FetchBlock(4)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), block.Block.Height)
+	assert.Equal(t, block.Block.Hash(), block.BlockID.Hash)
+	_, err = bs.FetchBlock(2)
+	assert.ErrorContains(t, err, "not found")
+	_, err = bs.FetchBlock(5)
+	assert.ErrorContains(t, err, "not found")
+
+	// commits come from the next block, or the seen commit of the last one
+	commit, err := bs.FetchCommit(4)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), commit.Height)
+	commit, err = bs.FetchCommit(6)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(6), commit.Height)
+
+	// replay stops at the missing height without claiming to be synced
+	c, err := bs.Subscribe(0)
+	assert.Nil(t, err)
+	var heights []int64
+	for block := range c {
+		heights = append(heights, block.Block.Height)
+	}
+	assert.Equal(t, []int64{3, 4}, heights)
+	assert.False(t, bs.IsSynced())
+}
+
+func TestBlockStoreSubscriptionSynced(t *testing.T) {
+	dir := t.TempDir()
+	writeBlockStore(t, dir, 1, 3, 0)
+
+	bs, err := NewBlockStoreSubscription(1, dir)
+	assert.Nil(t, err)
+	defer bs.Close()
+
+	c, err := bs.Subscribe(0)
+	assert.Nil(t, err)
+	var heights []int64
+	for block := range c {
+		heights = append(heights, block.Block.Height)
+	}
+	assert.Equal(t, []int64{2, 3}, heights)
+	assert.True(t, bs.IsSynced())
+}
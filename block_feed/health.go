@@ -0,0 +1,200 @@
+package block_feed
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// weight of the latest sample in latency/error moving averages
+	healthEWMAWeight = 0.2
+
+	// endpoints above this error rate are only used when healthy ones fail
+	healthyErrorRate = 0.5
+
+	backoffBase = 500 * time.Millisecond
+	backoffMax  = 30 * time.Second
+)
+
+// EndpointHealth is a snapshot of how well an endpoint has been serving us
+type EndpointHealth struct {
+	URL           string        `json:"url"`
+	Latency       time.Duration `json:"latency"`
+	ErrorRate     float64       `json:"error_rate"`
+	Successes     uint64        `json:"successes"`
+	Failures      uint64        `json:"failures"`
+	LastHeight    int64         `json:"last_height"`
+	HeightLag     int64         `json:"height_lag"`
+	LastError     string        `json:"last_error,omitempty"`
+	LastSuccessAt time.Time     `json:"last_success_at"`
+}
+
+// HealthTracker scores a set of endpoints by latency, error rate and height lag,
+// so that callers can always pick the healthiest one.
+type HealthTracker struct {
+	endpoints []*EndpointHealth
+	mtx       *sync.RWMutex
+}
+
+func NewHealthTracker(urls []string) *HealthTracker {
+	endpoints := make([]*EndpointHealth, len(urls))
+	for i, url := range urls {
+		endpoints[i] = &EndpointHealth{URL: url}
+	}
+
+	return &HealthTracker{
+		endpoints: endpoints,
+		mtx:       new(sync.RWMutex),
+	}
+}
+
+func (h *HealthTracker) URL(index int) string {
+	return h.endpoints[index].URL
+}
+
+func (h *HealthTracker) Len() int {
+	return len(h.endpoints)
+}
+
+// RecordSuccess records a successful request; height is the height served, or 0 if unknown
+func (h *HealthTracker) RecordSuccess(index int, latency time.Duration, height int64) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	e := h.endpoints[index]
+	e.Successes++
+	e.ErrorRate = e.ErrorRate * (1 - healthEWMAWeight)
+	if e.Latency == 0 {
+		e.Latency = latency
+	} else {
+		e.Latency = time.Duration(float64(e.Latency)*(1-healthEWMAWeight) + float64(latency)*healthEWMAWeight)
+	}
+	if height > e.LastHeight {
+		e.LastHeight = height
+	}
+	e.LastSuccessAt = time.Now()
+}
+
+// RecordHeight records a height served without a request, i.e. pushed through websocket
+func (h *HealthTracker) RecordHeight(index int, height int64) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	e := h.endpoints[index]
+	if height > e.LastHeight {
+		e.LastHeight = height
+	}
+	e.LastSuccessAt = time.Now()
+}
+
+func (h *HealthTracker) RecordFailure(index int, err error) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	e := h.endpoints[index]
+	e.Failures++
+	e.ErrorRate = e.ErrorRate*(1-healthEWMAWeight) + healthEWMAWeight
+	if err != nil {
+		e.LastError = err.Error()
+	}
+}
+
+// Snapshot returns health of all endpoints, with HeightLag computed against the highest known height
+func (h *HealthTracker) Snapshot() []EndpointHealth {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+
+	maxHeight := h.maxHeight()
+	out := make([]EndpointHealth, len(h.endpoints))
+	for i, e := range h.endpoints {
+		out[i] = *e
+		out[i].HeightLag = maxHeight - e.LastHeight
+	}
+	return out
+}
+
+// Ranked returns endpoint indexes from the healthiest to the least healthy
+func (h *HealthTracker) Ranked() []int {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+
+	maxHeight := h.maxHeight()
+	scores := make([]float64, len(h.endpoints))
+	ranked := make([]int, len(h.endpoints))
+	for i, e := range h.endpoints {
+		ranked[i] = i
+		scores[i] = score(e, maxHeight)
+	}
+
+	// insertion sort; endpoint lists are tiny, and the order of equal scores is kept
+	for i := 1; i < len(ranked); i++ {
+		for j := i; j > 0 && scores[ranked[j]] < scores[ranked[j-1]]; j-- {
+			ranked[j], ranked[j-1] = ranked[j-1], ranked[j]
+		}
+	}
+	return ranked
+}
+
+// Spread returns endpoint indexes like Ranked, but with healthy endpoints rotated by seed,
+// so that concurrent requests are spread across all healthy endpoints
+func (h *HealthTracker) Spread(seed int) []int {
+	ranked := h.Ranked()
+
+	h.mtx.RLock()
+	healthy := 0
+	for healthy < len(ranked) && h.endpoints[ranked[healthy]].ErrorRate < healthyErrorRate {
+		healthy++
+	}
+	h.mtx.RUnlock()
+
+	if healthy < 2 {
+		return ranked
+	}
+
+	offset := seed % healthy
+	spread := make([]int, 0, len(ranked))
+	spread = append(spread, ranked[offset:healthy]...)
+	spread = append(spread, ranked[:offset]...)
+	spread = append(spread, ranked[healthy:]...)
+	return spread
+}
+
+// Best returns the healthiest endpoint other than exclude; pass -1 to consider all
+func (h *HealthTracker) Best(exclude int) int {
+	ranked := h.Ranked()
+	for _, index := range ranked {
+		if index != exclude {
+			return index
+		}
+	}
+	return ranked[0]
+}
+
+func (h *HealthTracker) maxHeight() int64 {
+	var maxHeight int64
+	for _, e := range h.endpoints {
+		if e.LastHeight > maxHeight {
+			maxHeight = e.LastHeight
+		}
+	}
+	return maxHeight
+}
+
+// score is lower for healthier endpoints; errors weigh the most, then lag, then latency
+func score(e *EndpointHealth, maxHeight int64) float64 {
+	lag := float64(maxHeight - e.LastHeight)
+	return e.ErrorRate*100 + math.Min(lag, 100)*0.5 + e.Latency.Seconds()
+}
+
+// backoff returns exponentially growing wait time for the given attempt, capped at backoffMax
+func backoff(attempt int) time.Duration {
+	if attempt > 16 {
+		return backoffMax
+	}
+	wait := backoffBase * time.Duration(1<<attempt)
+	if wait > backoffMax {
+		return backoffMax
+	}
+	return wait
+}
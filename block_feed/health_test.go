@@ -0,0 +1,28 @@
+package block_feed
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthTracker(t *testing.T) {
+	h := NewHealthTracker([]string{"a", "b", "c"})
+	assert.Equal(t, []int{0, 1, 2}, h.Ranked())
+
+	// failing endpoint goes last
+	h.RecordFailure(0, fmt.Errorf("connection refused"))
+	assert.Equal(t, []int{1, 2, 0}, h.Ranked())
+
+	// lagging endpoint ranks below an up-to-date one
+	h.RecordSuccess(1, 10*time.Millisecond, 90)
+	h.RecordSuccess(2, 10*time.Millisecond, 100)
+	assert.Equal(t, 2, h.Best(-1))
+	assert.Equal(t, 1, h.Best(2))
+	assert.Equal(t, int64(10), h.Snapshot()[1].HeightLag)
+
+	assert.Equal(t, backoffBase, backoff(0))
+	assert.Equal(t, backoffMax, backoff(100))
+}
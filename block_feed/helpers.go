@@ -3,6 +3,7 @@ package block_feed
 import (
 	abci "github.com/cometbft/cometbft/abci/types"
 	tmjson "github.com/cometbft/cometbft/libs/json"
+	tendermint "github.com/cometbft/cometbft/types"
 )
 
 func extractBlockFromWSResponse(message []byte) (*BlockResult, error) {
@@ -46,3 +47,19 @@ func ExtractBlockResultFromRPCResponse(message []byte) ([]abci.ResponseDeliverTx
 
 	return data.Result.TxsResult, nil
 }
+
+func ExtractCommitFromRPCResponse(message []byte) (*tendermint.Commit, error) {
+	data := new(struct {
+		Result struct {
+			SignedHeader struct {
+				Commit *tendermint.Commit `json:"commit"`
+			} `json:"signed_header"`
+		} `json:"result"`
+	})
+
+	if err := tmjson.Unmarshal(message, data); err != nil {
+		return nil, err
+	}
+
+	return data.Result.SignedHeader.Commit, nil
+}
@@ -0,0 +1,204 @@
+package block_feed
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+var _ SyncAwareBlockFeed = (*QuorumSubscription)(nil)
+
+const (
+	quorumPollInterval = time.Second
+
+	// an endpoint that misses this many heights other endpoints agreed on is ejected for a while
+	quorumMaxLag         = 10
+	quorumLagEjectPeriod = time.Minute
+)
+
+type quorumEndpoint struct {
+	url string
+
+	// disagreeing endpoints are never re-admitted
+	disagreed    bool
+	lag          int
+	ejectedUntil time.Time
+}
+
+func (e *quorumEndpoint) isActive(now time.Time) bool {
+	return !e.disagreed && now.After(e.ejectedUntil)
+}
+
+// QuorumSubscription polls configured RPCs for each height, and only emits a block
+// once at least quorum endpoints have returned the same valid block; no more endpoints are polled than needed.
+// Endpoints returning a different block are ejected for good, and
+// endpoints lagging behind the others are ejected for quorumLagEjectPeriod.
+type QuorumSubscription struct {
+	endpoints      []*quorumEndpoint
+	quorum         int
+	lastKnownBlock int64
+	c              chan *BlockResult
+	isSynced       atomic.Bool
+
+	// ctx is cancelled by Close, stopping polling for good
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	fetch func(rpcEndpoint string, height int64) (*BlockResult, error)
+}
+
+func NewQuorumSubscription(currentBlock int64, rpcEndpoints []string, quorum int) (*QuorumSubscription, error) {
+	if quorum < 1 || quorum > len(rpcEndpoints) {
+		return nil, fmt.Errorf("invalid quorum %d for %d rpc endpoints", quorum, len(rpcEndpoints))
+	}
+
+	endpoints := make([]*quorumEndpoint, len(rpcEndpoints))
+	for i, url := range rpcEndpoints {
+		endpoints[i] = &quorumEndpoint{url: url}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &QuorumSubscription{
+		endpoints:      endpoints,
+		quorum:         quorum,
+		lastKnownBlock: currentBlock,
+		c:              make(chan *BlockResult),
+		ctx:            ctx,
+		cancel:         cancel,
+		fetch:          FetchBlock,
+	}, nil
+}
+
+// Subscribe starts polling from lastKnownBlock + 1; rpcIndex is ignored as all endpoints are used
+func (qs *QuorumSubscription) Subscribe(_ int) (chan *BlockResult, error) {
+	go func() {
+		for qs.ctx.Err() == nil {
+			if block := qs.fetchQuorum(qs.lastKnownBlock + 1); block != nil {
+				select {
+				case qs.c <- block:
+					qs.lastKnownBlock = block.Block.Height
+				case <-qs.ctx.Done():
+				}
+				continue
+			}
+
+			select {
+			case <-time.After(quorumPollInterval):
+			case <-qs.ctx.Done():
+			}
+		}
+	}()
+
+	return qs.c, nil
+}
+
+func (qs *QuorumSubscription) Close() error {
+	qs.cancel()
+	return nil
+}
+
+func (qs *QuorumSubscription) IsSynced() bool {
+	return qs.isSynced.Load()
+}
+
+func (qs *QuorumSubscription) setSyncState(state bool) {
+	qs.isSynced.Store(state)
+}
+
+// fetchQuorum returns the block at height agreed on by quorum endpoints, or nil if there is none yet
+func (qs *QuorumSubscription) fetchQuorum(height int64) *BlockResult {
+	now := time.Now()
+	active := make([]*quorumEndpoint, 0, len(qs.endpoints))
+	for _, endpoint := range qs.endpoints {
+		if endpoint.isActive(now) {
+			active = append(active, endpoint)
+		}
+	}
+
+	if len(active) < qs.quorum {
+		log.Printf("[block_feed/quorum] only %d endpoints are active, %d needed for quorum", len(active), qs.quorum)
+		return nil
+	}
+
+	// ask no more endpoints at once than could still make up the quorum
+	type answer struct {
+		index  int
+		result *BlockResult
+	}
+	answers := make(chan answer, len(active))
+	asked, inFlight, mostVotes := 0, 0, 0
+	askMore := func() {
+		for ; inFlight < qs.quorum-mostVotes && asked < len(active); asked, inFlight = asked+1, inFlight+1 {
+			go func(index int, url string) {
+				// failure here mostly means the endpoint doesn't have the block yet
+				result, _ := qs.fetch(url, height)
+				answers <- answer{index, result}
+			}(asked, active[asked].url)
+		}
+	}
+
+	results := make([]*BlockResult, len(active))
+	answered := make([]bool, len(active))
+	var agreed *BlockResult
+	votes := make(map[string]int)
+	for askMore(); inFlight > 0 && agreed == nil; askMore() {
+		a := <-answers
+		inFlight--
+		answered[a.index] = true
+
+		// Block.Hash() covers the header only; txs, last commit and evidence must match it
+		result := a.result
+		if result == nil || result.Block == nil {
+			continue
+		}
+		if err := result.Block.ValidateBasic(); err != nil {
+			log.Printf("[block_feed/quorum] ejecting %s; returned invalid block at height %d, %v", active[a.index].url, height, err)
+			active[a.index].disagreed = true
+			continue
+		}
+		results[a.index] = result
+		hash := string(result.Block.Hash())
+		votes[hash]++
+		mostVotes = max(mostVotes, votes[hash])
+		if votes[hash] >= qs.quorum {
+			agreed = result
+		}
+	}
+
+	if agreed == nil {
+		// nobody has the next block yet; we're at the tip of the chain
+		if len(votes) == 0 {
+			qs.setSyncState(true)
+		} else if len(votes) > 1 {
+			log.Printf("[block_feed/quorum] endpoints disagree on block %d, waiting for quorum", height)
+		}
+		return nil
+	}
+
+	// eject endpoints that disagree with the quorum or lag behind
+	agreedHash := agreed.Block.Hash()
+	for i, result := range results {
+		endpoint := active[i]
+		switch {
+		case !answered[i] || endpoint.disagreed:
+			// not asked, or still answering once quorum was reached
+		case result == nil:
+			endpoint.lag++
+			if endpoint.lag >= quorumMaxLag {
+				log.Printf("[block_feed/quorum] ejecting %s for %v; lagging %d blocks behind", endpoint.url, quorumLagEjectPeriod, endpoint.lag)
+				endpoint.lag = 0
+				endpoint.ejectedUntil = now.Add(quorumLagEjectPeriod)
+			}
+		case !bytes.Equal(result.Block.Hash(), agreedHash):
+			log.Printf("[block_feed/quorum] ejecting %s; returned block %X at height %d, quorum agreed on %X", endpoint.url, result.Block.Hash(), height, agreedHash)
+			endpoint.disagreed = true
+		default:
+			endpoint.lag = 0
+		}
+	}
+
+	return agreed
+}
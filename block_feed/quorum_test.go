@@ -0,0 +1,106 @@
+package block_feed
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	tendermint "github.com/cometbft/cometbft/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func quorumTestBlock(txs ...string) *BlockResult {
+	blockTxs := make([]tendermint.Tx, len(txs))
+	for i, tx := range txs {
+		blockTxs[i] = tendermint.Tx(tx)
+	}
+	block := tendermint.MakeBlock(1, blockTxs, &tendermint.Commit{}, nil)
+	block.ProposerAddress = make([]byte, 20)
+	// headers without validators hash have no hash
+	block.ValidatorsHash = make([]byte, 32)
+	return &BlockResult{Block: block}
+}
+
+func TestFetchQuorum(t *testing.T) {
+	honest := quorumTestBlock("a")
+	forked := quorumTestBlock("b")
+	// same header as honest, so the same Block.Hash(), but different txs
+	tampered := quorumTestBlock("a")
+	tampered.Block.Data = tendermint.Data{Txs: []tendermint.Tx{tendermint.Tx("c")}}
+
+	for name, tc := range map[string]struct {
+		quorum    int
+		ejected   []string
+		blocks    map[string]*BlockResult
+		agreed    *BlockResult
+		asked     []string
+		disagreed []string
+	}{
+		"agree": {
+			quorum: 2,
+			blocks: map[string]*BlockResult{"a": honest, "b": honest, "c": honest},
+			agreed: honest,
+			asked:  []string{"a", "b"},
+		},
+		"disagree": {
+			quorum:    2,
+			blocks:    map[string]*BlockResult{"a": honest, "b": forked, "c": honest},
+			agreed:    honest,
+			asked:     []string{"a", "b", "c"},
+			disagreed: []string{"b"},
+		},
+		"eject invalid": {
+			quorum:    2,
+			blocks:    map[string]*BlockResult{"a": tampered, "b": honest, "c": honest},
+			agreed:    honest,
+			asked:     []string{"a", "b", "c"},
+			disagreed: []string{"a"},
+		},
+		"no quorum": {
+			quorum: 2,
+			blocks: map[string]*BlockResult{"a": honest, "b": forked, "c": nil},
+			asked:  []string{"a", "b", "c"},
+		},
+		"not enough endpoints": {
+			quorum:  2,
+			ejected: []string{"b", "c"},
+			blocks:  map[string]*BlockResult{"a": honest, "b": honest, "c": honest},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			qs, err := NewQuorumSubscription(0, []string{"a", "b", "c"}, tc.quorum)
+			assert.Nil(t, err)
+
+			mtx := new(sync.Mutex)
+			var asked []string
+			qs.fetch = func(url string, height int64) (*BlockResult, error) {
+				mtx.Lock()
+				asked = append(asked, url)
+				mtx.Unlock()
+				if tc.blocks[url] == nil {
+					return nil, fmt.Errorf("no block at %d", height)
+				}
+				return tc.blocks[url], nil
+			}
+			for _, endpoint := range qs.endpoints {
+				for _, url := range tc.ejected {
+					endpoint.disagreed = endpoint.disagreed || endpoint.url == url
+				}
+			}
+
+			assert.Equal(t, tc.agreed, qs.fetchQuorum(1))
+			mtx.Lock()
+			assert.ElementsMatch(t, tc.asked, asked)
+			mtx.Unlock()
+
+			var disagreed []string
+			for _, endpoint := range qs.endpoints {
+				if endpoint.disagreed && !slices.Contains(tc.ejected, endpoint.url) {
+					disagreed = append(disagreed, endpoint.url)
+				}
+			}
+			assert.ElementsMatch(t, tc.disagreed, disagreed)
+		})
+	}
+}
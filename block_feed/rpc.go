@@ -1,59 +1,310 @@
 package block_feed
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	tendermint "github.com/cometbft/cometbft/types"
+)
+
+var (
+	_ BlockFeed    = (*RPCSubscription)(nil)
+	_ BlockFetcher = (*RPCSubscription)(nil)
 )
 
-var _ BlockFeed = (*RPCSubscription)(nil)
+// httpClient is shared by all rpc requests; a hung endpoint must not stall syncing forever
+var httpClient = &http.Client{Timeout: 30 * time.Second}
 
 type RPCSubscription struct {
 	rpcEndpoints []string
 	cSub         chan *BlockResult
+	health       *HealthTracker
+	windowSize   int
+	maxRetries   int
+	withResults  bool
+	withCommits  bool
+
+	// ctx is cancelled by Close, stopping every sync started before
+	mtx    sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
-func NewRpcSubscription(rpcEndpoints []string) (*RPCSubscription, error) {
+// NewRpcSubscription creates a subscription that prefetches up to windowSize blocks concurrently
+func NewRpcSubscription(rpcEndpoints []string, windowSize int) (*RPCSubscription, error) {
+	if windowSize < 1 {
+		return nil, fmt.Errorf("invalid rpc sync window size %d", windowSize)
+	}
+	if len(rpcEndpoints) == 0 {
+		return nil, fmt.Errorf("no rpc endpoints")
+	}
+	for _, rpcEndpoint := range rpcEndpoints {
+		if u, err := url.Parse(rpcEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid rpc endpoint %q; expected http[s]://host[:port]", rpcEndpoint)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
 	return &RPCSubscription{
 		rpcEndpoints: rpcEndpoints,
 		cSub:         make(chan *BlockResult),
+		health:       NewHealthTracker(rpcEndpoints),
+		windowSize:   windowSize,
+		ctx:          ctx,
+		cancel:       cancel,
 	}, nil
 }
 
-func (rpc *RPCSubscription) SyncFromUntil(from int64, to int64, rpcIndex int) {
+// SyncFromUntil fetches blocks [from, to] and delivers them in order, followed by done.
+// Up to windowSize heights are fetched concurrently, spread across healthy endpoints,
+// so that fetching the next blocks overlaps with injecting the current one.
+// rpcIndex is ignored; endpoints are picked by HealthTracker.
+// Fetching stops once the subscription is closed or SyncFromUntil returns, whichever comes first;
+// a sync stopped before delivering every block returns an error instead of delivering done.
+func (rpc *RPCSubscription) SyncFromUntil(from int64, to int64, _ int) error {
 	cSub := rpc.cSub
+	ctx, cancel := context.WithCancel(rpc.context())
+	defer cancel()
 
-	log.Printf("[block_feed/rpc] subscription started, from=%d, to=%d\n", from, to)
+	log.Printf("[block_feed/rpc] subscription started, from=%d, to=%d, window=%d\n", from, to, rpc.windowSize)
+
+	type fetched struct {
+		block *BlockResult
+		err   error
+	}
+
+	// each pending height gets its own result channel; reading them in order of
+	// heights keeps delivery ordered while fetches complete in any order
+	pending := make(chan chan fetched, rpc.windowSize)
+	go func() {
+		defer close(pending)
+		for i := from; i <= to; i++ {
+			result := make(chan fetched, 1)
+			select {
+			case pending <- result:
+			case <-ctx.Done():
+				return
+			}
+
+			go func(height int64) {
+				block, err := rpc.fetchWithFailover(ctx, height)
+				result <- fetched{block, err}
+			}(i)
+		}
+	}()
 
 	// is a blocking operation
-	for i := from; i <= to; i++ {
-		log.Printf("[block_feed/rpc] receiving block %d...\n", i)
-		url := fmt.Sprintf("%s/block?height=%d", rpc.rpcEndpoints[rpcIndex], i)
-		res, err := http.Get(url)
-		if err != nil {
-			log.Fatalf("block request failed, %v", err)
+	next := from
+	for result := range pending {
+		f := <-result
+		if f.err != nil {
+			return f.err
 		}
+		block := f.block
+		log.Printf("[block_feed/rpc] received block %d\n", block.Block.Height)
+		select {
+		case cSub <- block:
+			next++
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if next <= to {
+		return fmt.Errorf("subscription closed; blocks %d..%d were not delivered", next, to)
+	}
 
-		resBytes, err := io.ReadAll(res.Body)
-		if err != nil {
-			log.Fatalf("block request failed, %v", err)
+	select {
+	case cSub <- done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("subscription closed")
+	}
+}
+
+// fetchWithFailover fetches the block at height, along with its results and commit if asked to
+func (rpc *RPCSubscription) fetchWithFailover(ctx context.Context, height int64) (*BlockResult, error) {
+	var block *BlockResult
+	err := rpc.failover(ctx, height, func(rpcEndpoint string) (err error) {
+		block, err = FetchBlock(rpcEndpoint, height)
+		if err == nil && rpc.withResults {
+			block.Results, err = FetchBlockResults(rpcEndpoint, height)
 		}
+		if err == nil && rpc.withCommits {
+			block.Commit, err = FetchCommit(rpcEndpoint, height)
+		}
+		return err
+	})
+	return block, err
+}
 
-		if block, blockParseErr := ExtractBlockFromRPCResponse(resBytes); blockParseErr != nil {
-			log.Fatalf("block parse failed, %v", err)
-		} else {
-			cSub <- block
+// failover tries every endpoint starting from a healthy one, and backs off exponentially
+// when all of them fail. It gives up when ctx is done, or after maxRetries rounds if set.
+func (rpc *RPCSubscription) failover(ctx context.Context, height int64, fetch func(rpcEndpoint string) error) error {
+	var lastErr error
+	for attempt := 0; ctx.Err() == nil; attempt++ {
+		for _, index := range rpc.health.Spread(int(height)) {
+			start := time.Now()
+			if err := fetch(rpc.health.URL(index)); err != nil {
+				log.Printf("[block_feed/rpc] block %d request to %s failed, %v", height, rpc.health.URL(index), err)
+				rpc.health.RecordFailure(index, err)
+				lastErr = err
+				continue
+			}
+
+			rpc.health.RecordSuccess(index, time.Since(start), height)
+			return nil
+		}
+
+		if rpc.maxRetries > 0 && attempt >= rpc.maxRetries {
+			return fmt.Errorf("all endpoints failed for block %d after %d retries, %w", height, attempt, lastErr)
+		}
+
+		wait := backoff(attempt)
+		log.Printf("[block_feed/rpc] all endpoints failed for block %d, retrying in %v", height, wait)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
 		}
 	}
 
-	cSub <- nil
+	return fmt.Errorf("subscription closed before block %d was fetched", height)
+}
+
+// FetchBlock fetches a single block with failover
+func (rpc *RPCSubscription) FetchBlock(height int64) (*BlockResult, error) {
+	return rpc.fetchWithFailover(rpc.context(), height)
+}
+
+// FetchCommit fetches the commit of a single block with failover
+func (rpc *RPCSubscription) FetchCommit(height int64) (*tendermint.Commit, error) {
+	var commit *tendermint.Commit
+	err := rpc.failover(rpc.context(), height, func(rpcEndpoint string) (err error) {
+		commit, err = FetchCommit(rpcEndpoint, height)
+		return err
+	})
+	return commit, err
+}
+
+// SetMaxRetries makes fetching give up once every endpoint failed this many more times;
+// 0, the default, retries until the subscription is closed
+func (rpc *RPCSubscription) SetMaxRetries(maxRetries int) {
+	rpc.maxRetries = maxRetries
 }
 
+// FetchResults makes SyncFromUntil fetch block_results along with every block, within the same window
+func (rpc *RPCSubscription) FetchResults(enabled bool) {
+	rpc.withResults = enabled
+}
+
+// FetchCommits makes SyncFromUntil fetch the commit of every block, within the same window
+func (rpc *RPCSubscription) FetchCommits(enabled bool) {
+	rpc.withCommits = enabled
+}
+
+// Health returns health of all rpc endpoints
+func (rpc *RPCSubscription) Health() []EndpointHealth {
+	return rpc.health.Snapshot()
+}
+
+// FetchBlock gets a single block from the /block endpoint of an RPC
+func FetchBlock(rpcEndpoint string, height int64) (*BlockResult, error) {
+	url := fmt.Sprintf("%s/block?height=%d", rpcEndpoint, height)
+	res, err := httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	resBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := ExtractBlockFromRPCResponse(resBytes)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil || block.Block == nil {
+		return nil, fmt.Errorf("block %d not found in response from %s", height, rpcEndpoint)
+	}
+
+	return block, nil
+}
+
+// FetchCommit gets the commit signing a single block from the /commit endpoint of an RPC
+func FetchCommit(rpcEndpoint string, height int64) (*tendermint.Commit, error) {
+	url := fmt.Sprintf("%s/commit?height=%d", rpcEndpoint, height)
+	res, err := httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	resBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	commit, err := ExtractCommitFromRPCResponse(resBytes)
+	if err != nil {
+		return nil, err
+	}
+	if commit == nil || commit.Height != height {
+		return nil, fmt.Errorf("commit %d not found in response from %s", height, rpcEndpoint)
+	}
+
+	return commit, nil
+}
+
+// Subscribe reopens a closed subscription; syncs started before Close stay stopped
 func (rpc *RPCSubscription) Subscribe(_ int) (chan *BlockResult, error) {
+	rpc.mtx.Lock()
+	defer rpc.mtx.Unlock()
+	if rpc.ctx.Err() != nil {
+		rpc.ctx, rpc.cancel = context.WithCancel(context.Background())
+	}
 	return rpc.cSub, nil
 }
 
 func (rpc *RPCSubscription) Close() error {
+	rpc.mtx.Lock()
+	defer rpc.mtx.Unlock()
+	rpc.cancel()
 	return nil
 }
+
+func (rpc *RPCSubscription) context() context.Context {
+	rpc.mtx.Lock()
+	defer rpc.mtx.Unlock()
+	return rpc.ctx
+}
+
+// FetchBlockResults gets DeliverTx results of a single block from the /block_results endpoint of an RPC
+func FetchBlockResults(rpcEndpoint string, height int64) ([]abci.ResponseDeliverTx, error) {
+	url := fmt.Sprintf("%s/block_results?height=%d", rpcEndpoint, height)
+	res, err := httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("block_results request for height %d failed with status %d", height, res.StatusCode)
+	}
+
+	resBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return ExtractBlockResultFromRPCResponse(resBytes)
+}
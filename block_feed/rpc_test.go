@@ -0,0 +1,131 @@
+package block_feed
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	tmjson "github.com/cometbft/cometbft/libs/json"
+	tendermint "github.com/cometbft/cometbft/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRPCSyncStopsOnClose(t *testing.T) {
+	for name, serveBlocks := range map[string]bool{
+		// nobody reads the channel, so delivery blocks
+		"blocked on delivery": true,
+		// every endpoint fails, so fetches back off
+		"backing off": false,
+	} {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !serveBlocks {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				height, _ := strconv.ParseInt(r.URL.Query().Get("height"), 10, 64)
+				block := tendermint.MakeBlock(height, nil, &tendermint.Commit{}, nil)
+				body, _ := tmjson.Marshal(map[string]*BlockResult{"result": {BlockID: &tendermint.BlockID{}, Block: block}})
+				_, _ = w.Write(body)
+			}))
+			defer server.Close()
+
+			rpc, err := NewRpcSubscription([]string{server.URL}, 4)
+			assert.Nil(t, err)
+			_, _ = rpc.Subscribe(0)
+
+			returned := make(chan error)
+			go func() {
+				returned <- rpc.SyncFromUntil(1, 100, 0)
+			}()
+
+			time.Sleep(100 * time.Millisecond)
+			assert.Nil(t, rpc.Close())
+			select {
+			case err := <-returned:
+				// consumers waiting for done learn that it never comes
+				assert.ErrorContains(t, err, "closed")
+			case <-time.After(5 * time.Second):
+				t.Fatal("SyncFromUntil kept running after Close")
+			}
+
+			// a renewed subscription doesn't bring the stopped sync back
+			_, _ = rpc.Subscribe(0)
+			assert.Nil(t, rpc.context().Err())
+		})
+	}
+}
+
+func TestRPCFetchBlockFailover(t *testing.T) {
+	dead := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer dead.Close()
+	alive := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		height, _ := strconv.ParseInt(r.URL.Query().Get("height"), 10, 64)
+		block := tendermint.MakeBlock(height, nil, &tendermint.Commit{}, nil)
+		body, _ := tmjson.Marshal(map[string]*BlockResult{"result": {BlockID: &tendermint.BlockID{}, Block: block}})
+		_, _ = w.Write(body)
+	}))
+	defer alive.Close()
+
+	rpc, err := NewRpcSubscription([]string{dead.URL, alive.URL}, 1)
+	assert.Nil(t, err)
+	defer rpc.Close()
+
+	for height := int64(1); height <= 4; height++ {
+		block, err := rpc.FetchBlock(height)
+		assert.Nil(t, err)
+		assert.Equal(t, height, block.Block.Height)
+	}
+
+	assert.Nil(t, rpc.Close())
+	_, err = rpc.FetchBlock(5)
+	assert.ErrorContains(t, err, "closed")
+}
+
+func TestRPCSyncGivesUp(t *testing.T) {
+	dead := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer dead.Close()
+
+	rpc, err := NewRpcSubscription([]string{dead.URL}, 1)
+	assert.Nil(t, err)
+	defer rpc.Close()
+	rpc.SetMaxRetries(1)
+
+	_, _ = rpc.Subscribe(0)
+	err = rpc.SyncFromUntil(1, 3, 0)
+	assert.ErrorContains(t, err, "after 1 retries")
+}
+
+func TestNewRpcSubscriptionEndpoints(t *testing.T) {
+	for _, endpoints := range [][]string{nil, {""}, {"localhost:26657"}, {"ws://localhost:26657"}} {
+		_, err := NewRpcSubscription(endpoints, 1)
+		assert.NotNil(t, err, "%q", endpoints)
+	}
+}
+
+func TestFetchCommit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		height, _ := strconv.ParseInt(r.URL.Query().Get("height"), 10, 64)
+		commit := &tendermint.Commit{Height: height, BlockID: tendermint.BlockID{Hash: []byte{1}}}
+		response := struct {
+			Result struct {
+				SignedHeader tendermint.SignedHeader `json:"signed_header"`
+			} `json:"result"`
+		}{}
+		response.Result.SignedHeader.Commit = commit
+		body, _ := tmjson.Marshal(response)
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	commit, err := FetchCommit(server.URL, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), commit.Height)
+	assert.Equal(t, []byte{1}, []byte(commit.BlockID.Hash))
+}
@@ -0,0 +1,41 @@
+package block_feed
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const EndpointGETBlockFeedStatus = "/block_feed/status"
+
+// StatusBlockFeed is a BlockFeed that can report on its own progress
+type StatusBlockFeed interface {
+	BlockFeed
+
+	// Status returns a snapshot of the feed state
+	Status() FeedStatus
+}
+
+// FeedStatus is served under EndpointGETBlockFeedStatus
+type FeedStatus struct {
+	Synced              bool                        `json:"synced"`
+	Stalled             bool                        `json:"stalled"`
+	LastHeight          int64                       `json:"last_height"`
+	LastBlockReceivedAt time.Time                   `json:"last_block_received_at"`
+	Endpoints           map[string][]EndpointHealth `json:"endpoints"`
+}
+
+// RegisterStatusRoute exposes feed.Status under EndpointGETBlockFeedStatus
+func RegisterStatusRoute(router *mux.Router, feed StatusBlockFeed) {
+	router.HandleFunc(EndpointGETBlockFeedStatus, func(writer http.ResponseWriter, request *http.Request) {
+		statusJSON, err := json.Marshal(feed.Status())
+		if err != nil {
+			http.Error(writer, err.Error(), 500)
+			return
+		}
+		writer.WriteHeader(200)
+		writer.Write(statusJSON)
+	}).Methods("GET")
+}
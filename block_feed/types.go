@@ -1,6 +1,9 @@
 package block_feed
 
 import (
+	"fmt"
+
+	abci "github.com/cometbft/cometbft/abci/types"
 	tendermint "github.com/cometbft/cometbft/types"
 )
 
@@ -15,7 +18,51 @@ type BlockFeed interface {
 	Subscribe(rpcIndex int) (chan *BlockResult, error)
 }
 
+// SyncAwareBlockFeed is a BlockFeed that knows whether it has caught up with the chain
+type SyncAwareBlockFeed interface {
+	BlockFeed
+
+	// IsSynced returns true once the feed has reached the tip of the chain
+	IsSynced() bool
+}
+
+// HaltingBlockFeed is a BlockFeed that may stop for good when the block stream becomes inconsistent
+type HaltingBlockFeed interface {
+	BlockFeed
+
+	// Halted delivers the reason once the feed has stopped
+	Halted() <-chan error
+}
+
+// BlockFetcher fetches single blocks and their commits by height,
+// e.g. to reindex blocks injected earlier or to verify the block being injected
+type BlockFetcher interface {
+	// FetchBlock returns the block at height
+	FetchBlock(height int64) (*BlockResult, error)
+
+	// FetchCommit returns the commit signing the block at height
+	FetchCommit(height int64) (*tendermint.Commit, error)
+
+	// Close releases the underlying block source
+	Close() error
+}
+
+// ForkError is raised when a block doesn't extend the chain of blocks received so far
+type ForkError struct {
+	Height   int64
+	Expected []byte
+	Actual   []byte
+}
+
+func (e *ForkError) Error() string {
+	return fmt.Sprintf("fork detected at height %d; expected block hash %X, got %X", e.Height, e.Expected, e.Actual)
+}
+
 type BlockResult struct {
 	BlockID *tendermint.BlockID `json:"block_id"`
 	Block   *tendermint.Block   `json:"block"`
+	// Results are DeliverTx results of the block, only set by feeds asked to fetch them
+	Results []abci.ResponseDeliverTx `json:"-"`
+	// Commit signs the block, only set by feeds asked to fetch it
+	Commit *tendermint.Commit `json:"-"`
 }
@@ -3,16 +3,22 @@ package block_feed
 import (
 	"encoding/json"
 	"log"
+	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
 var _ BlockFeed = (*WSSubscription)(nil)
 
+const defaultWSPingInterval = 10 * time.Second
+
 type WSSubscription struct {
-	wsEndpoints []string
-	ws          *websocket.Conn
-	c           chan *BlockResult
+	wsEndpoints  []string
+	mtx          sync.Mutex // guards ws, replaced by Subscribe while the stall detector may Close
+	ws           *websocket.Conn
+	c            chan *BlockResult
+	pingInterval time.Duration
 }
 
 type handshake struct {
@@ -28,11 +34,18 @@ type params struct {
 
 func NewWSSubscription(wsEndpoints []string) (*WSSubscription, error) {
 	return &WSSubscription{
-		wsEndpoints: wsEndpoints,
-		ws:          nil,
+		wsEndpoints:  wsEndpoints,
+		ws:           nil,
+		pingInterval: defaultWSPingInterval,
 	}, nil
 }
 
+// SetPingInterval sets how often the websocket is pinged.
+// A connection that answers neither pings nor sends messages for twice the interval is closed.
+func (ws *WSSubscription) SetPingInterval(interval time.Duration) {
+	ws.pingInterval = interval
+}
+
 func (ws *WSSubscription) Subscribe(rpcIndex int) (chan *BlockResult, error) {
 	socket, _, err := websocket.DefaultDialer.Dial(ws.wsEndpoints[rpcIndex], nil)
 	// return err, handle failures gracefully
@@ -40,7 +53,9 @@ func (ws *WSSubscription) Subscribe(rpcIndex int) (chan *BlockResult, error) {
 		return nil, err
 	}
 
+	ws.mtx.Lock()
 	ws.ws = socket
+	ws.mtx.Unlock()
 
 	request := &handshake{
 		JSONRPC: "2.0",
@@ -54,13 +69,13 @@ func (ws *WSSubscription) Subscribe(rpcIndex int) (chan *BlockResult, error) {
 	log.Print("Subscribing to tendermint rpc...")
 
 	// should not fail here
-	if err := ws.ws.WriteJSON(request); err != nil {
+	if err := socket.WriteJSON(request); err != nil {
 		return nil, err
 	}
 
 	// handle initial message
 	// by setting c.initialized to true, we prevent message mishandling
-	if err := handleInitialHandhake(ws.ws); err != nil {
+	if err := handleInitialHandhake(socket); err != nil {
 		return nil, err
 	}
 
@@ -70,13 +85,25 @@ func (ws *WSSubscription) Subscribe(rpcIndex int) (chan *BlockResult, error) {
 	c := make(chan *BlockResult)
 	ws.c = c
 
-	go receiveBlockEvents(ws.ws, c)
+	// keepalive; half-open connections are detected by missing pongs
+	readTimeout := 2 * ws.pingInterval
+	socket.SetPongHandler(func(string) error {
+		return socket.SetReadDeadline(time.Now().Add(readTimeout))
+	})
+	go keepAlive(socket, ws.pingInterval)
+
+	go receiveBlockEvents(socket, c, readTimeout)
 
 	// start receiving blocks
 	return c, nil
 }
 
 func (ws *WSSubscription) Close() error {
+	ws.mtx.Lock()
+	defer ws.mtx.Unlock()
+	if ws.ws == nil {
+		return nil
+	}
 	return ws.ws.Close()
 }
 
@@ -92,10 +119,24 @@ func handleInitialHandhake(ws *websocket.Conn) error {
 	return nil
 }
 
+// keepAlive pings the connection until a ping fails, i.e. the connection is closed
+func keepAlive(ws *websocket.Conn, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(interval)); err != nil {
+			return
+		}
+	}
+}
+
 // TODO: handle errors here
-func receiveBlockEvents(ws *websocket.Conn, c chan *BlockResult) {
+func receiveBlockEvents(ws *websocket.Conn, c chan *BlockResult, readTimeout time.Duration) {
 	defer close(c)
 	for {
+		// deadline is extended by every pong as well
+		_ = ws.SetReadDeadline(time.Now().Add(readTimeout))
 		_, message, err := ws.ReadMessage()
 		// if read message failed,
 		// scrap the whole ws thing
@@ -0,0 +1,53 @@
+package block_feed
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWSCloseWhileResubscribing(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// answer the subscribe request, then hold the connection until the client closes it
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+		_ = conn.WriteJSON(map[string]interface{}{"jsonrpc": "2.0", "id": 0, "result": map[string]interface{}{}})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	ws, err := NewWSSubscription([]string{"ws" + strings.TrimPrefix(server.URL, "http")})
+	assert.Nil(t, err)
+
+	// the stall detector closes the connection while the feed reconnects
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for i := 0; i < 20; i++ {
+			_ = ws.Close()
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		c, err := ws.Subscribe(0)
+		assert.Nil(t, err)
+		_ = ws.Close()
+		for range c {
+		}
+	}
+	<-closed
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	blockFeeder "github.com/terra-money/mantlemint/block_feed"
+	"github.com/terra-money/mantlemint/config"
+)
+
+// newBlocksCmd groups commands on block archives
+func newBlocksCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "blocks",
+		Short: "Manage block archives",
+	}
+	cmd.AddCommand(newBlocksExportCmd())
+	return cmd
+}
+
+// newBlocksExportCmd handles `mantlemint blocks export --from <height> --to <height> --output <file>`.
+// It pulls blocks (and optionally block_results) from the configured rpc endpoints into a block archive,
+// which can be replayed later by setting ARCHIVE_PATH.
+func newBlocksExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export a range of blocks from RPC into a block archive",
+		Args:  cobra.NoArgs,
+	}
+	from := cmd.Flags().Int64("from", 1, "first height to export")
+	to := cmd.Flags().Int64("to", 0, "last height to export")
+	output := cmd.Flags().String("output", "blocks.mmba", "archive file to write")
+	withResults := cmd.Flags().Bool("with-results", false, "include block_results in the archive")
+	window := cmd.Flags().Int("window", 16, "number of blocks to fetch concurrently")
+	maxRetries := cmd.Flags().Int("max-retries", 10, "times to retry a block once every endpoint failed, before giving up; 0 retries forever")
+
+	cmd.RunE = func(cmd *cobra.Command, _ []string) error {
+		mantlemintConfig, configErr := config.NewConfig(cmd.Flags())
+		if configErr != nil {
+			return configErr
+		}
+		return exportBlocks(*from, *to, *output, *withResults, mantlemintConfig.RPCEndpoints, *window, *maxRetries)
+	}
+
+	return cmd
+}
+
+func exportBlocks(from, to int64, output string, withResults bool, rpcEndpoints []string, window, maxRetries int) error {
+	if to < from || from < 1 {
+		return fmt.Errorf("[blocks/export] invalid range %d..%d", from, to)
+	}
+	if len(rpcEndpoints) == 0 {
+		return fmt.Errorf("[blocks/export] no rpc endpoints to export from; set RPC_ENDPOINTS or sync.endpoints")
+	}
+	if maxRetries < 0 {
+		return fmt.Errorf("[blocks/export] invalid max retries %d", maxRetries)
+	}
+
+	rpc, rpcErr := blockFeeder.NewRpcSubscription(rpcEndpoints, window)
+	if rpcErr != nil {
+		return rpcErr
+	}
+	defer rpc.Close()
+	// block_results and commits are fetched along with blocks, within the same window;
+	// commits let replays verify blocks with verify.commits
+	rpc.FetchResults(withResults)
+	rpc.FetchCommits(true)
+	rpc.SetMaxRetries(maxRetries)
+
+	// stop fetching on SIGINT/SIGTERM; the partial archive is left behind as a .tmp file
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	stopRPC := context.AfterFunc(ctx, func() { _ = rpc.Close() })
+	defer stopRPC()
+
+	cRpc, _ := rpc.Subscribe(0)
+	syncErr := make(chan error, 1)
+	go func() {
+		syncErr <- rpc.SyncFromUntil(from, to, 0)
+	}()
+
+	// write to a temporary file so that a partial archive is never mistaken for a complete one
+	tmpOutput := output + ".tmp"
+	file, fileErr := os.Create(tmpOutput)
+	if fileErr != nil {
+		return fileErr
+	}
+	defer file.Close()
+
+	writer, writerErr := blockFeeder.NewArchiveWriter(file, from, to)
+	if writerErr != nil {
+		return writerErr
+	}
+
+	for {
+		var block *blockFeeder.BlockResult
+		select {
+		case block = <-cRpc:
+		case err := <-syncErr:
+			if err != nil {
+				return fmt.Errorf("[blocks/export] %w", err)
+			}
+			// every block was delivered; only the end of the range is left to read
+			block = <-cRpc
+		}
+		if block == nil {
+			break
+		}
+
+		record := &blockFeeder.ArchiveRecord{BlockID: block.BlockID, Block: block.Block, Commit: block.Commit, Results: block.Results}
+
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+		if block.Block.Height%1000 == 0 {
+			log.Printf("[blocks/export] exported up to %d", block.Block.Height)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpOutput, output); err != nil {
+		return err
+	}
+
+	log.Printf("[blocks/export] exported blocks %d..%d to %s", from, to, output)
+	return nil
+}
@@ -0,0 +1,111 @@
+package terra
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/CosmWasm/wasmd/x/wasm"
+	wasmtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	terraapp "github.com/classic-terra/core/v3/app"
+	"github.com/classic-terra/core/v3/app/params"
+	core "github.com/classic-terra/core/v3/types"
+	dbm "github.com/cometbft/cometbft-db"
+	tmlog "github.com/cometbft/cometbft/libs/log"
+	"github.com/cosmos/cosmos-sdk/baseapp"
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/server/api"
+	"github.com/cosmos/cosmos-sdk/server/config"
+	servertypes "github.com/cosmos/cosmos-sdk/server/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/terra-money/mantlemint/mantlemint"
+)
+
+var (
+	_ mantlemint.AppFactory            = (*AppFactory)(nil)
+	_ mantlemint.UpgradeHandlerChecker = (*AppFactory)(nil)
+)
+
+// AppFactory runs Terra Classic
+type AppFactory struct {
+	encodingConfig params.EncodingConfig
+}
+
+func NewAppFactory() mantlemint.AppFactory {
+	return &AppFactory{
+		encodingConfig: terraapp.MakeEncodingConfig(),
+	}
+}
+
+func (f *AppFactory) ConfigureSDK(sdkConfig *sdk.Config) {
+	sdkConfig.SetCoinType(core.CoinType)
+	sdkConfig.SetBech32PrefixForAccount(core.Bech32PrefixAccAddr, core.Bech32PrefixAccPub)
+	sdkConfig.SetBech32PrefixForValidator(core.Bech32PrefixValAddr, core.Bech32PrefixValPub)
+	sdkConfig.SetBech32PrefixForConsensusNode(core.Bech32PrefixConsAddr, core.Bech32PrefixConsPub)
+	sdkConfig.SetAddressVerifier(wasmtypes.VerifyAddressLen())
+}
+
+func (f *AppFactory) EncodingConfig() mantlemint.EncodingConfig {
+	return mantlemint.EncodingConfig{
+		InterfaceRegistry: f.encodingConfig.InterfaceRegistry,
+		Codec:             f.encodingConfig.Marshaler,
+		TxConfig:          f.encodingConfig.TxConfig,
+		Amino:             f.encodingConfig.Amino,
+	}
+}
+
+func (f *AppFactory) NewApp(
+	logger tmlog.Logger,
+	db dbm.DB,
+	home string,
+	appOpts servertypes.AppOptions,
+	baseAppOptions ...func(*baseapp.BaseApp),
+) servertypes.Application {
+	logWasmConfig(appOpts)
+
+	// keeper options change how contracts execute; mantlemint runs the keeper exactly as the chain does.
+	// VM tuning goes through appOpts instead
+	var wasmOpts []wasm.Option
+
+	return terraapp.NewTerraApp(
+		logger,
+		db,
+		nil,
+		true, // need this so KVStores are set
+		make(map[int64]bool),
+		home,
+		f.encodingConfig,
+		appOpts,
+		wasmOpts,
+		baseAppOptions...,
+	)
+}
+
+func (f *AppFactory) RegisterAPIRoutes(app servertypes.Application, apiSrv *api.Server, apiConfig config.APIConfig, clientCtx client.Context) {
+	app.RegisterAPIRoutes(apiSrv, apiConfig)
+	app.RegisterTendermintService(clientCtx)
+}
+
+// HasUpgradeHandler tells whether app registered a handler for the named x/upgrade plan
+func (f *AppFactory) HasUpgradeHandler(app servertypes.Application, name string) bool {
+	return app.(*terraapp.TerraApp).UpgradeKeeper.HasHandler(name)
+}
+
+// logWasmConfig prints wasm VM settings the app is about to run with
+func logWasmConfig(opts servertypes.AppOptions) {
+	wasmConfig, err := wasm.ReadWasmConfig(opts)
+	if err != nil {
+		panic(err)
+	}
+
+	simulationGasLimit := "unlimited"
+	if wasmConfig.SimulationGasLimit != nil {
+		simulationGasLimit = fmt.Sprintf("%d", *wasmConfig.SimulationGasLimit)
+	}
+	log.Printf(
+		"[app] wasm memory_cache_size=%dMiB query_gas_limit=%d simulation_gas_limit=%s contract_debug_mode=%v",
+		wasmConfig.MemoryCacheSize,
+		wasmConfig.SmartQueryGasLimit,
+		simulationGasLimit,
+		wasmConfig.ContractDebugMode,
+	)
+}
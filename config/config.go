@@ -4,67 +4,223 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
-	terra "github.com/classic-terra/core/v3/app"
-	"github.com/cosmos/cosmos-sdk/x/crisis"
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"github.com/terra-money/mantlemint/mantlemint"
 )
 
 type Config struct {
 	GenesisPath  string
 	Home         string
 	ChainID      string
+	App          string
+	Upgrades     []mantlemint.AppUpgrade
 	RPCEndpoints []string
 	WSEndpoints  []string
 	MantlemintDB string
 	IndexerDB    string
+	DBBackend    string
 	DisableSync  bool
+
+	RollbackJournalSize int64
+	ResultsHashPolicy   string
+
+	PruningKeepRecent int64
+	PruningKeepEvery  int64
+	PruningInterval   time.Duration
+
+	TieringColdDir       string
+	TieringPartitionSize int64
+	TieringKeepHot       int64
+	TieringInterval      time.Duration
+
+	VerifyBlockResults bool
+	VerifyRPCEndpoint  string
+	VerifyCommits      bool
+
+	Quorum int
+
+	RPCSyncWindow int
+
+	WSPingInterval time.Duration
+	StallTimeout   time.Duration
+
+	BlockStoreDir string
+	ArchivePath   string
+
+	HaltHeight int64
+	HaltTime   int64
+
+	CacheSize         int
+	ArchivalCacheSize int
+	Indexers          []string
+	APIAddress        string
+
+	WasmMemoryCacheSize    uint32
+	WasmQueryGasLimit      uint64
+	WasmSimulationGasLimit uint64
+	WasmContractDebugMode  bool
+
+	// ConfigFile is the mantlemint.toml this config was read from, if any
+	ConfigFile string
 }
 
-// NewConfig converts envvars into consumable config chunks
-func NewConfig() Config {
-	cfg := Config{
-		// GenesisPath sets the location of genesis
-		GenesisPath: getValidEnv("GENESIS_PATH"),
-
-		// Home sets where the default terra home is.
-		Home: getValidEnv("MANTLEMINT_HOME"),
-
-		// ChainID sets expected chain id for this mantlemint instance
-		ChainID: getValidEnv("CHAIN_ID"),
-
-		// RPCEndpoints is where to pull txs from when fast-syncing
-		RPCEndpoints: func() []string {
-			endpoints := getValidEnv("RPC_ENDPOINTS")
-			return strings.Split(endpoints, ",")
-		}(),
-
-		// WSEndpoints is where to pull txs from when normal syncing
-		WSEndpoints: func() []string {
-			endpoints := getValidEnv("WS_ENDPOINTS")
-			return strings.Split(endpoints, ",")
-		}(),
-
-		// MantlemintDB is the db name for mantlemint. Defaults to terra.DefaultHome
-		MantlemintDB: func() string {
-			mantlemintDB := getValidEnv("MANTLEMINT_DB")
-			if mantlemintDB == "" {
-				return terra.DefaultNodeHome
-			} else {
-				return mantlemintDB
-			}
-		}(),
+// backends mantlemint db can be stored with
+const (
+	DBBackendGoLevelDB = "goleveldb"
+	DBBackendPebble    = "pebble"
+	// DBBackendMemDB keeps mantlemint db and indexer db in memory, for throwaway instances
+	DBBackendMemDB = "memdb"
+)
+
+// option is a single setting of mantlemint.toml, along with the env var and flag overriding it
+type option struct {
+	key          string
+	env          string
+	defaultValue interface{}
+}
+
+var options = []option{
+	// GenesisPath sets the location of genesis
+	{"genesis_path", "GENESIS_PATH", ""},
+	// Home sets where the default terra home is.
+	{"home", "MANTLEMINT_HOME", ""},
+	// ChainID sets expected chain id for this mantlemint instance
+	{"chain_id", "CHAIN_ID", ""},
+	// App selects the chain mantlemint runs from genesis, until the first of [[upgrades]] takes over;
+	// see newAppFactory for the available apps
+	{"app", "MANTLEMINT_APP", "terra-classic"},
+
+	// DisableSync sets a flag where if true mantlemint won't accept any blocks (usually for debugging)
+	{"sync.disable", "DISABLE_SYNC", false},
+	// RPCSyncWindow is how many blocks are fetched concurrently when catching up over RPC
+	{"sync.rpc_sync_window", "RPC_SYNC_WINDOW", 16},
+	// Quorum, if set, makes mantlemint poll every RPC endpoint and only accept blocks
+	// that at least Quorum endpoints agree on, instead of following a websocket
+	{"sync.quorum", "QUORUM", 0},
+	// WSPingInterval is how often websocket connections are pinged, in seconds.
+	// A connection that stays silent for twice the interval is dropped
+	{"sync.ws_ping_interval", "WS_PING_INTERVAL", 10},
+	// StallTimeout makes mantlemint reconnect to another endpoint when no new block
+	// arrived for this many seconds. 0 disables stall detection
+	{"sync.stall_timeout", "STALL_TIMEOUT", 60},
+	// BlockStoreDir, if set, makes mantlemint replay blocks from blockstore.db in this directory
+	// (usually $TERRA_HOME/data of a stopped node) instead of syncing over network
+	{"sync.blockstore_dir", "BLOCKSTORE_DIR", ""},
+	// ArchivePath, if set, makes mantlemint replay blocks from a block archive
+	// created by `mantlemint blocks export`
+	{"sync.archive_path", "ARCHIVE_PATH", ""},
+	// HaltHeight and HaltTime (unix seconds) stop syncing after committing the block at or past them,
+	// as halt-height and halt-time of app.toml do. Queries keep being served. 0 disables either
+	{"sync.halt_height", "HALT_HEIGHT", 0},
+	{"sync.halt_time", "HALT_TIME", 0},
+
+	// VerifyCommits enables light-client verification of every incoming block against its own commit
+	{"verify.commits", "VERIFY_COMMITS", false},
+	// VerifyBlockResults enables cross-checking DeliverTx results against upstream /block_results,
+	// or against results in the archive when replaying one
+	{"verify.block_results", "VERIFY_BLOCK_RESULTS", false},
+	// VerifyRPCEndpoint is the RPC to cross-check against. Defaults to the first RPC endpoint
+	{"verify.rpc_endpoint", "VERIFY_RPC_ENDPOINT", ""},
+	// ResultsHashPolicy decides what to do when local execution results diverge from the chain;
+	// one of log, halt, rollback
+	{"verify.results_hash_policy", "RESULTS_HASH_POLICY", "log"},
+
+	// MantlemintDB is the db name for mantlemint
+	{"storage.mantlemint_db", "MANTLEMINT_DB", "mantlemint"},
+	// IndexerDB is the db name for indexed data
+	{"storage.indexer_db", "INDEXER_DB", "indexer"},
+	// DBBackend is what mantlemint db is stored with; one of goleveldb, pebble, memdb.
+	// An existing db can't switch backends; restore a snapshot into a fresh home instead
+	{"storage.backend", "MANTLEMINT_DB_BACKEND", DBBackendGoLevelDB},
+	// RollbackJournalSize sets how many recent blocks can be reverted with `mantlemint rollback`.
+	// 0 disables the journal
+	{"storage.rollback_journal_size", "ROLLBACK_JOURNAL_SIZE", 100},
+
+	// PruningKeepRecent and PruningKeepEvery keep the last keep_recent heights queryable,
+	// plus every keep_every-th height below them; state of other heights is deleted in the background.
+	// 0 keep_recent keeps every height. keep_every can't change once pruned
+	{"pruning.keep_recent", "PRUNING_KEEP_RECENT", 0},
+	{"pruning.keep_every", "PRUNING_KEEP_EVERY", 0},
+	// PruningInterval is how often pruning runs, in seconds
+	{"pruning.interval", "PRUNING_INTERVAL", 600},
+
+	// TieringColdDir, if set, moves state only read by heights older than the last keep_hot heights
+	// out of mantlemint db, into LevelDB partitions of partition_size heights under it.
+	// Can't be combined with pruning, and partition_size can't change once history was moved
+	{"tiering.cold_dir", "TIERING_COLD_DIR", ""},
+	{"tiering.partition_size", "TIERING_PARTITION_SIZE", 1000000},
+	{"tiering.keep_hot", "TIERING_KEEP_HOT", 1000000},
+	// TieringInterval is how often history is checked for moving, in seconds
+	{"tiering.interval", "TIERING_INTERVAL", 600},
+
+	// CacheSize and ArchivalCacheSize are the number of responses kept in the LCD response caches,
+	// for latest and historical (?height=) queries respectively
+	{"cache.latest_size", "CACHE_SIZE", 16384},
+	{"cache.archival_size", "ARCHIVAL_CACHE_SIZE", 16384},
 
-		// IndexerDB is the db name for indexed data
-		IndexerDB: getValidEnv("INDEXER_DB"),
+	// Indexers to run; indexers not listed here are neither run nor served
+	{"indexer.enabled", "INDEXERS", []string{"tx", "block"}},
+
+	// APIAddress overrides api.address of app.toml
+	{"api.address", "API_ADDRESS", ""},
+
+	// wasm VM settings, overriding [wasm] of app.toml; 0 leaves app.toml as is.
+	// WasmMemoryCacheSize is in MiB
+	{"wasm.memory_cache_size", "WASM_MEMORY_CACHE_SIZE", 0},
+	{"wasm.query_gas_limit", "WASM_QUERY_GAS_LIMIT", 0},
+	{"wasm.simulation_gas_limit", "WASM_SIMULATION_GAS_LIMIT", 0},
+	// WasmContractDebugMode prints contract debug messages
+	{"wasm.contract_debug_mode", "WASM_CONTRACT_DEBUG_MODE", false},
+}
+
+// flags overriding both mantlemint.toml and env vars
+const (
+	FlagConfig       = "config"
+	FlagHome         = "home"
+	FlagChainID      = "chain-id"
+	FlagGenesisPath  = "genesis-path"
+	FlagRPCEndpoints = "rpc-endpoints"
+	FlagWSEndpoints  = "ws-endpoints"
+	FlagHaltHeight   = "halt-height"
+	FlagHaltTime     = "halt-time"
+)
+
+var flagKeys = map[string]string{
+	FlagHome:        "home",
+	FlagChainID:     "chain_id",
+	FlagGenesisPath: "genesis_path",
+	FlagHaltHeight:  "sync.halt_height",
+	FlagHaltTime:    "sync.halt_time",
+}
 
-		// DisableSync sets a flag where if true mantlemint won't accept any blocks (usually for debugging)
-		DisableSync: func() bool {
-			disableSync := getValidEnv("DISABLE_SYNC")
-			return disableSync == "true"
-		}(),
+// AddFlags registers flags that Load understands
+func AddFlags(flags *pflag.FlagSet) {
+	flags.String(FlagConfig, "", "path to mantlemint.toml; defaults to $MANTLEMINT_CONFIG, then $MANTLEMINT_HOME/config/"+DefaultConfigFileName+" if present")
+	flags.String(FlagHome, "", "mantlemint home directory ($MANTLEMINT_HOME)")
+	flags.String(FlagChainID, "", "expected chain id ($CHAIN_ID)")
+	flags.String(FlagGenesisPath, "", "location of genesis.json ($GENESIS_PATH)")
+	flags.String(FlagRPCEndpoints, "", "comma separated rpc endpoints ($RPC_ENDPOINTS)")
+	flags.String(FlagWSEndpoints, "", "comma separated websocket endpoints, in the same order as rpc endpoints ($WS_ENDPOINTS)")
+	flags.Int64(FlagHaltHeight, 0, "stop syncing after committing this height ($HALT_HEIGHT)")
+	flags.Int64(FlagHaltTime, 0, "stop syncing after committing the first block at or past this unix time ($HALT_TIME)")
+}
+
+// NewConfig loads and validates mantlemint config,
+// then merges app.toml under $MANTLEMINT_HOME/config into the global viper.
+// flags may be nil.
+func NewConfig(flags *pflag.FlagSet) (Config, error) {
+	cfg, err := Load(flags)
+	if err != nil {
+		return cfg, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, err
 	}
 
 	viper.SetConfigType("toml")
@@ -72,27 +228,126 @@ func NewConfig() Config {
 	viper.AutomaticEnv()
 	viper.AddConfigPath(filepath.Join(cfg.Home, "config"))
 
-	pflag.Bool(crisis.FlagSkipGenesisInvariants, false, "Skip x/crisis invariants check on startup")
-	pflag.Parse()
-	if bindErr := viper.BindPFlags(pflag.CommandLine); bindErr != nil {
-		panic(bindErr)
+	if err := viper.MergeInConfig(); err != nil {
+		return cfg, fmt.Errorf("failed to merge configuration: %w", err)
 	}
 
-	if err := viper.MergeInConfig(); err != nil {
-		panic(fmt.Errorf("failed to merge configuration: %w", err))
+	return cfg, nil
+}
+
+// Load merges, from lowest to highest precedence, defaults, mantlemint.toml, env vars and flags.
+// The result is not validated.
+func Load(flags *pflag.FlagSet) (Config, error) {
+	v := viper.New()
+	v.SetConfigType("toml")
+
+	for _, opt := range options {
+		v.SetDefault(opt.key, opt.defaultValue)
+		if err := v.BindEnv(opt.key, opt.env); err != nil {
+			return Config{}, err
+		}
+	}
+	if flags != nil {
+		for flag, key := range flagKeys {
+			if f := flags.Lookup(flag); f != nil {
+				if err := v.BindPFlag(key, f); err != nil {
+					return Config{}, err
+				}
+			}
+		}
+	}
+
+	configFile := lookupString(flags, FlagConfig, "MANTLEMINT_CONFIG")
+	if configFile == "" && v.GetString("home") != "" {
+		defaultConfigFile := filepath.Join(v.GetString("home"), "config", DefaultConfigFileName)
+		if _, err := os.Stat(defaultConfigFile); err == nil {
+			configFile = defaultConfigFile
+		}
+	}
+	if configFile != "" {
+		v.SetConfigFile(configFile)
+		if err := v.ReadInConfig(); err != nil {
+			return Config{}, fmt.Errorf("failed to read %s: %w", configFile, err)
+		}
+	}
+
+	var fc fileConfig
+	if err := v.Unmarshal(&fc, func(dc *mapstructure.DecoderConfig) {
+		dc.TagName = "toml"
+		// typos in mantlemint.toml
+		dc.ErrorUnused = true
+	}); err != nil {
+		return Config{}, fmt.Errorf("failed to parse configuration: %w", err)
 	}
 
-	return cfg
+	// endpoints are pairs in mantlemint.toml, and separate lists in env vars and flags
+	rpcEndpoints := splitList(lookupString(flags, FlagRPCEndpoints, "RPC_ENDPOINTS"))
+	wsEndpoints := splitList(lookupString(flags, FlagWSEndpoints, "WS_ENDPOINTS"))
+	if rpcEndpoints != nil || wsEndpoints != nil {
+		fileRPCEndpoints, fileWSEndpoints := fc.endpointLists()
+		if rpcEndpoints == nil {
+			rpcEndpoints = fileRPCEndpoints
+		}
+		if wsEndpoints == nil {
+			wsEndpoints = fileWSEndpoints
+		}
+		fc.Sync.Endpoints = zipEndpoints(rpcEndpoints, wsEndpoints)
+	}
+
+	// upgrades are tables in mantlemint.toml, and height:app pairs in the env var
+	if upgrades := splitList(os.Getenv("APP_UPGRADES")); upgrades != nil {
+		fc.Upgrades = nil
+		for _, upgrade := range upgrades {
+			height, app, ok := strings.Cut(upgrade, ":")
+			parsedHeight, err := strconv.ParseInt(height, 10, 64)
+			if !ok || err != nil {
+				return Config{}, fmt.Errorf("invalid configuration: APP_UPGRADES: expected height:app, got %q", upgrade)
+			}
+			fc.Upgrades = append(fc.Upgrades, upgradeConfig{Height: parsedHeight, App: app})
+		}
+	}
+
+	// endpoints are picked by index; a websocket can't be skipped
+	for i := 1; i < len(fc.Sync.Endpoints); i++ {
+		if fc.Sync.Endpoints[i].WS != "" && fc.Sync.Endpoints[i-1].WS == "" {
+			return Config{}, fmt.Errorf("invalid configuration: sync.endpoints[%d] has no ws, while sync.endpoints[%d] does", i-1, i)
+		}
+	}
+
+	cfg := fc.toConfig()
+	cfg.ConfigFile = configFile
+	if cfg.VerifyRPCEndpoint == "" && len(cfg.RPCEndpoints) > 0 {
+		cfg.VerifyRPCEndpoint = cfg.RPCEndpoints[0]
+	}
+
+	return cfg, nil
 }
 
 func (cfg Config) Print() {
-	fmt.Println(cfg)
+	out, err := cfg.TOML()
+	if err != nil {
+		fmt.Println(cfg)
+		return
+	}
+	if cfg.ConfigFile != "" {
+		fmt.Printf("# merged from %s, env vars and flags\n", cfg.ConfigFile)
+	}
+	fmt.Print(string(out))
+}
+
+// lookupString returns the flag if set, or else the env var
+func lookupString(flags *pflag.FlagSet, flag string, env string) string {
+	if flags != nil {
+		if f := flags.Lookup(flag); f != nil && f.Changed {
+			return f.Value.String()
+		}
+	}
+	return os.Getenv(env)
 }
 
-func getValidEnv(tag string) string {
-	if e := os.Getenv(tag); e == "" {
-		panic(fmt.Errorf("environment variable %s not set; expected string, got %s \"\"", tag, e))
-	} else {
-		return e
+func splitList(list string) []string {
+	if list == "" {
+		return nil
 	}
+	return strings.Split(list, ",")
 }
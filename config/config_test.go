@@ -0,0 +1,114 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/terra-money/mantlemint/mantlemint"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	genesisPath := filepath.Join(dir, "genesis.json")
+	assert.Nil(t, os.WriteFile(genesisPath, []byte("{}"), 0o600))
+
+	configFile := filepath.Join(dir, "mantlemint.toml")
+	assert.Nil(t, os.WriteFile(configFile, []byte(`
+home = "`+dir+`"
+chain_id = "columbus-5"
+genesis_path = "`+genesisPath+`"
+
+[[upgrades]]
+height = 100
+app = "terra-classic"
+
+[sync]
+stall_timeout = 30
+
+[[sync.endpoints]]
+rpc = "http://rpc1:26657"
+ws = "ws://rpc1:26657/websocket"
+
+[[sync.endpoints]]
+rpc = "http://rpc2:26657"
+ws = "ws://rpc2:26657/websocket"
+`), 0o600))
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	AddFlags(flags)
+	assert.Nil(t, flags.Parse([]string{"--config", configFile, "--chain-id", "columbus-6"}))
+
+	// env overrides file, flag overrides env
+	t.Setenv("CHAIN_ID", "rebel-2")
+	t.Setenv("QUORUM", "2")
+	t.Setenv("RPC_ENDPOINTS", "http://rpc3:26657,http://rpc4:26657")
+
+	cfg, err := Load(flags)
+	assert.Nil(t, err)
+	assert.Equal(t, configFile, cfg.ConfigFile)
+	assert.Equal(t, "columbus-6", cfg.ChainID)
+	assert.Equal(t, 2, cfg.Quorum)
+	assert.Equal(t, 30*time.Second, cfg.StallTimeout)
+	assert.Equal(t, []string{"http://rpc3:26657", "http://rpc4:26657"}, cfg.RPCEndpoints)
+	assert.Equal(t, []string{"ws://rpc1:26657/websocket", "ws://rpc2:26657/websocket"}, cfg.WSEndpoints)
+	assert.Equal(t, "http://rpc3:26657", cfg.VerifyRPCEndpoint)
+	assert.Equal(t, []mantlemint.AppUpgrade{{Height: 100, App: "terra-classic"}}, cfg.Upgrades)
+
+	// defaults
+	assert.Equal(t, int64(100), cfg.RollbackJournalSize)
+	assert.Equal(t, DBBackendGoLevelDB, cfg.DBBackend)
+	assert.Equal(t, int64(0), cfg.PruningKeepRecent)
+	assert.Equal(t, 600*time.Second, cfg.PruningInterval)
+	assert.Equal(t, "", cfg.TieringColdDir)
+	assert.Equal(t, int64(1000000), cfg.TieringPartitionSize)
+	assert.Equal(t, []string{"tx", "block"}, cfg.Indexers)
+	assert.Nil(t, cfg.Validate())
+
+	cfg.Quorum = 3
+	cfg.ResultsHashPolicy = "ignore"
+	cfg.DBBackend = "rocksdb"
+	cfg.PruningKeepRecent = 50
+	cfg.TieringColdDir = filepath.Join(dir, "cold")
+	assert.ErrorContains(t, cfg.Validate(), "sync.quorum")
+	assert.ErrorContains(t, cfg.Validate(), "verify.results_hash_policy")
+	assert.ErrorContains(t, cfg.Validate(), "storage.backend")
+	assert.ErrorContains(t, cfg.Validate(), "pruning.keep_recent")
+	assert.ErrorContains(t, cfg.Validate(), "can't be combined with pruning")
+
+	Apps = []string{"terra-classic"}
+	defer func() { Apps = nil }()
+	cfg.Upgrades = append(cfg.Upgrades, mantlemint.AppUpgrade{Height: 200, App: "terra-classic-v4"})
+	assert.ErrorContains(t, cfg.Validate(), `upgrades[1].app: unknown app "terra-classic-v4"`)
+}
+
+func TestLoadUnknownKey(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "mantlemint.toml")
+	assert.Nil(t, os.WriteFile(configFile, []byte("[sync]\nquorom = 1\n"), 0o600))
+	t.Setenv("MANTLEMINT_CONFIG", configFile)
+
+	_, err := Load(nil)
+	assert.ErrorContains(t, err, "quorom")
+}
+
+func TestValidateLocalReplay(t *testing.T) {
+	dir := t.TempDir()
+	genesisPath := filepath.Join(dir, "genesis.json")
+	assert.Nil(t, os.WriteFile(genesisPath, []byte("{}"), 0o600))
+	t.Setenv("MANTLEMINT_HOME", dir)
+	t.Setenv("CHAIN_ID", "columbus-5")
+	t.Setenv("GENESIS_PATH", genesisPath)
+	t.Setenv("RPC_ENDPOINTS", "")
+	t.Setenv("WS_ENDPOINTS", "")
+
+	cfg, err := Load(nil)
+	assert.Nil(t, err)
+	assert.ErrorContains(t, cfg.Validate(), "at least one rpc endpoint is required")
+
+	// replaying a blockstore needs no endpoints
+	cfg.BlockStoreDir = filepath.Join(dir, "data")
+	assert.Nil(t, cfg.Validate())
+}
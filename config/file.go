@@ -0,0 +1,254 @@
+package config
+
+import (
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/terra-money/mantlemint/mantlemint"
+)
+
+// DefaultConfigFileName is looked up under $MANTLEMINT_HOME/config when no config file is given
+const DefaultConfigFileName = "mantlemint.toml"
+
+// fileConfig is the layout of mantlemint.toml
+type fileConfig struct {
+	Home        string `toml:"home"`
+	ChainID     string `toml:"chain_id"`
+	GenesisPath string `toml:"genesis_path"`
+	App         string `toml:"app"`
+
+	Upgrades []upgradeConfig `toml:"upgrades"`
+
+	Sync    syncConfig    `toml:"sync"`
+	Verify  verifyConfig  `toml:"verify"`
+	Storage storageConfig `toml:"storage"`
+	Pruning pruningConfig `toml:"pruning"`
+	Tiering tieringConfig `toml:"tiering"`
+	Cache   cacheConfig   `toml:"cache"`
+	Indexer indexerConfig `toml:"indexer"`
+	API     apiConfig     `toml:"api"`
+	Wasm    wasmConfig    `toml:"wasm"`
+}
+
+// upgradeConfig switches to app from height on
+type upgradeConfig struct {
+	Height int64  `toml:"height"`
+	App    string `toml:"app"`
+}
+
+type syncConfig struct {
+	Disable        bool             `toml:"disable"`
+	Endpoints      []endpointConfig `toml:"endpoints"`
+	RPCSyncWindow  int              `toml:"rpc_sync_window"`
+	Quorum         int              `toml:"quorum"`
+	WSPingInterval int64            `toml:"ws_ping_interval"`
+	StallTimeout   int64            `toml:"stall_timeout"`
+	BlockStoreDir  string           `toml:"blockstore_dir"`
+	ArchivePath    string           `toml:"archive_path"`
+	HaltHeight     int64            `toml:"halt_height"`
+	HaltTime       int64            `toml:"halt_time"`
+}
+
+// endpointConfig is a single upstream node; ws is the websocket of the same node as rpc
+type endpointConfig struct {
+	RPC string `toml:"rpc"`
+	WS  string `toml:"ws"`
+}
+
+type verifyConfig struct {
+	Commits           bool   `toml:"commits"`
+	BlockResults      bool   `toml:"block_results"`
+	RPCEndpoint       string `toml:"rpc_endpoint"`
+	ResultsHashPolicy string `toml:"results_hash_policy"`
+}
+
+type storageConfig struct {
+	MantlemintDB        string `toml:"mantlemint_db"`
+	IndexerDB           string `toml:"indexer_db"`
+	Backend             string `toml:"backend"`
+	RollbackJournalSize int64  `toml:"rollback_journal_size"`
+}
+
+type pruningConfig struct {
+	KeepRecent int64 `toml:"keep_recent"`
+	KeepEvery  int64 `toml:"keep_every"`
+	Interval   int64 `toml:"interval"`
+}
+
+type tieringConfig struct {
+	ColdDir       string `toml:"cold_dir"`
+	PartitionSize int64  `toml:"partition_size"`
+	KeepHot       int64  `toml:"keep_hot"`
+	Interval      int64  `toml:"interval"`
+}
+
+type cacheConfig struct {
+	LatestSize   int `toml:"latest_size"`
+	ArchivalSize int `toml:"archival_size"`
+}
+
+type indexerConfig struct {
+	Enabled []string `toml:"enabled"`
+}
+
+type apiConfig struct {
+	Address string `toml:"address"`
+}
+
+type wasmConfig struct {
+	MemoryCacheSize    uint32 `toml:"memory_cache_size"`
+	QueryGasLimit      uint64 `toml:"query_gas_limit"`
+	SimulationGasLimit uint64 `toml:"simulation_gas_limit"`
+	ContractDebugMode  bool   `toml:"contract_debug_mode"`
+}
+
+func (fc fileConfig) toConfig() Config {
+	cfg := Config{
+		GenesisPath:  fc.GenesisPath,
+		Home:         fc.Home,
+		ChainID:      fc.ChainID,
+		App:          fc.App,
+		MantlemintDB: fc.Storage.MantlemintDB,
+		IndexerDB:    fc.Storage.IndexerDB,
+		DBBackend:    fc.Storage.Backend,
+		DisableSync:  fc.Sync.Disable,
+
+		RollbackJournalSize: fc.Storage.RollbackJournalSize,
+		ResultsHashPolicy:   fc.Verify.ResultsHashPolicy,
+
+		PruningKeepRecent: fc.Pruning.KeepRecent,
+		PruningKeepEvery:  fc.Pruning.KeepEvery,
+		PruningInterval:   time.Duration(fc.Pruning.Interval) * time.Second,
+
+		TieringColdDir:       fc.Tiering.ColdDir,
+		TieringPartitionSize: fc.Tiering.PartitionSize,
+		TieringKeepHot:       fc.Tiering.KeepHot,
+		TieringInterval:      time.Duration(fc.Tiering.Interval) * time.Second,
+
+		VerifyBlockResults: fc.Verify.BlockResults,
+		VerifyRPCEndpoint:  fc.Verify.RPCEndpoint,
+		VerifyCommits:      fc.Verify.Commits,
+
+		Quorum:         fc.Sync.Quorum,
+		RPCSyncWindow:  fc.Sync.RPCSyncWindow,
+		WSPingInterval: time.Duration(fc.Sync.WSPingInterval) * time.Second,
+		StallTimeout:   time.Duration(fc.Sync.StallTimeout) * time.Second,
+		BlockStoreDir:  fc.Sync.BlockStoreDir,
+		ArchivePath:    fc.Sync.ArchivePath,
+		HaltHeight:     fc.Sync.HaltHeight,
+		HaltTime:       fc.Sync.HaltTime,
+
+		CacheSize:         fc.Cache.LatestSize,
+		ArchivalCacheSize: fc.Cache.ArchivalSize,
+		Indexers:          fc.Indexer.Enabled,
+		APIAddress:        fc.API.Address,
+
+		WasmMemoryCacheSize:    fc.Wasm.MemoryCacheSize,
+		WasmQueryGasLimit:      fc.Wasm.QueryGasLimit,
+		WasmSimulationGasLimit: fc.Wasm.SimulationGasLimit,
+		WasmContractDebugMode:  fc.Wasm.ContractDebugMode,
+	}
+
+	for _, upgrade := range fc.Upgrades {
+		cfg.Upgrades = append(cfg.Upgrades, mantlemint.AppUpgrade{Height: upgrade.Height, App: upgrade.App})
+	}
+	for _, endpoint := range fc.Sync.Endpoints {
+		cfg.RPCEndpoints = append(cfg.RPCEndpoints, endpoint.RPC)
+		if endpoint.WS != "" {
+			cfg.WSEndpoints = append(cfg.WSEndpoints, endpoint.WS)
+		}
+	}
+
+	return cfg
+}
+
+func (cfg Config) toFileConfig() fileConfig {
+	fc := fileConfig{
+		Home:        cfg.Home,
+		ChainID:     cfg.ChainID,
+		GenesisPath: cfg.GenesisPath,
+		App:         cfg.App,
+		Sync: syncConfig{
+			Disable:        cfg.DisableSync,
+			RPCSyncWindow:  cfg.RPCSyncWindow,
+			Quorum:         cfg.Quorum,
+			WSPingInterval: int64(cfg.WSPingInterval / time.Second),
+			StallTimeout:   int64(cfg.StallTimeout / time.Second),
+			BlockStoreDir:  cfg.BlockStoreDir,
+			ArchivePath:    cfg.ArchivePath,
+			HaltHeight:     cfg.HaltHeight,
+			HaltTime:       cfg.HaltTime,
+		},
+		Verify: verifyConfig{
+			Commits:           cfg.VerifyCommits,
+			BlockResults:      cfg.VerifyBlockResults,
+			RPCEndpoint:       cfg.VerifyRPCEndpoint,
+			ResultsHashPolicy: cfg.ResultsHashPolicy,
+		},
+		Storage: storageConfig{
+			MantlemintDB:        cfg.MantlemintDB,
+			IndexerDB:           cfg.IndexerDB,
+			Backend:             cfg.DBBackend,
+			RollbackJournalSize: cfg.RollbackJournalSize,
+		},
+		Pruning: pruningConfig{
+			KeepRecent: cfg.PruningKeepRecent,
+			KeepEvery:  cfg.PruningKeepEvery,
+			Interval:   int64(cfg.PruningInterval / time.Second),
+		},
+		Tiering: tieringConfig{
+			ColdDir:       cfg.TieringColdDir,
+			PartitionSize: cfg.TieringPartitionSize,
+			KeepHot:       cfg.TieringKeepHot,
+			Interval:      int64(cfg.TieringInterval / time.Second),
+		},
+		Cache: cacheConfig{
+			LatestSize:   cfg.CacheSize,
+			ArchivalSize: cfg.ArchivalCacheSize,
+		},
+		Indexer: indexerConfig{
+			Enabled: cfg.Indexers,
+		},
+		API: apiConfig{
+			Address: cfg.APIAddress,
+		},
+		Wasm: wasmConfig{
+			MemoryCacheSize:    cfg.WasmMemoryCacheSize,
+			QueryGasLimit:      cfg.WasmQueryGasLimit,
+			SimulationGasLimit: cfg.WasmSimulationGasLimit,
+			ContractDebugMode:  cfg.WasmContractDebugMode,
+		},
+	}
+
+	for _, upgrade := range cfg.Upgrades {
+		fc.Upgrades = append(fc.Upgrades, upgradeConfig{Height: upgrade.Height, App: upgrade.App})
+	}
+	fc.Sync.Endpoints = zipEndpoints(cfg.RPCEndpoints, cfg.WSEndpoints)
+
+	return fc
+}
+
+func (fc fileConfig) endpointLists() (rpcEndpoints []string, wsEndpoints []string) {
+	for _, endpoint := range fc.Sync.Endpoints {
+		rpcEndpoints = append(rpcEndpoints, endpoint.RPC)
+		wsEndpoints = append(wsEndpoints, endpoint.WS)
+	}
+	return rpcEndpoints, wsEndpoints
+}
+
+// zipEndpoints pairs rpc and websocket endpoints by index
+func zipEndpoints(rpcEndpoints, wsEndpoints []string) []endpointConfig {
+	endpoints := make([]endpointConfig, max(len(rpcEndpoints), len(wsEndpoints)))
+	for i := range rpcEndpoints {
+		endpoints[i].RPC = rpcEndpoints[i]
+	}
+	for i := range wsEndpoints {
+		endpoints[i].WS = wsEndpoints[i]
+	}
+	return endpoints
+}
+
+// TOML returns cfg in the format of mantlemint.toml
+func (cfg Config) TOML() ([]byte, error) {
+	return toml.Marshal(cfg.toFileConfig())
+}
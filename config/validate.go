@@ -0,0 +1,180 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/terra-money/mantlemint/mantlemint"
+)
+
+// Apps are the app names this build can run; main sets them from its app factories.
+// Left empty, app names aren't checked.
+var Apps []string
+
+// Validate checks cfg as a whole, reporting every problem at once
+func (cfg Config) Validate() error {
+	var problems []string
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if cfg.Home == "" {
+		problem("home is required (MANTLEMINT_HOME)")
+	}
+	if cfg.ChainID == "" {
+		problem("chain_id is required (CHAIN_ID)")
+	}
+	if cfg.App == "" {
+		problem("app is required (MANTLEMINT_APP)")
+	} else if _, err := mantlemint.NewAppSchedule(cfg.App, cfg.Upgrades); err != nil {
+		problem("upgrades: %v", err)
+	}
+	if cfg.App != "" && !isKnownApp(cfg.App) {
+		problem("app: unknown app %q; available: %s", cfg.App, strings.Join(Apps, ", "))
+	}
+	for i, upgrade := range cfg.Upgrades {
+		// an app this build doesn't ship needs another mantlemint binary to cross the upgrade
+		if !isKnownApp(upgrade.App) {
+			problem("upgrades[%d].app: unknown app %q; available: %s", i, upgrade.App, strings.Join(Apps, ", "))
+		}
+	}
+	if cfg.GenesisPath == "" {
+		problem("genesis_path is required (GENESIS_PATH)")
+	} else if _, err := os.Stat(cfg.GenesisPath); err != nil {
+		problem("genesis_path: %v", err)
+	}
+
+	// replaying a local blockstore or archive takes no network at all
+	replaysLocally := cfg.BlockStoreDir != "" || cfg.ArchivePath != ""
+	if len(cfg.RPCEndpoints) == 0 && !replaysLocally {
+		problem("sync.endpoints: at least one rpc endpoint is required (RPC_ENDPOINTS), unless sync.blockstore_dir or sync.archive_path is set")
+	}
+	for i, endpoint := range cfg.RPCEndpoints {
+		if err := validateURL(endpoint, "http", "https"); err != nil {
+			problem("sync.endpoints[%d].rpc: %v", i, err)
+		}
+	}
+	for i, endpoint := range cfg.WSEndpoints {
+		if err := validateURL(endpoint, "ws", "wss"); err != nil {
+			problem("sync.endpoints[%d].ws: %v", i, err)
+		}
+	}
+
+	followsWebsocket := !cfg.DisableSync && cfg.Quorum == 0 && cfg.BlockStoreDir == "" && cfg.ArchivePath == ""
+	if followsWebsocket && len(cfg.WSEndpoints) == 0 {
+		problem("sync.endpoints: at least one ws endpoint is required (WS_ENDPOINTS), unless sync.quorum, sync.blockstore_dir or sync.archive_path is set")
+	}
+	if cfg.BlockStoreDir != "" && cfg.ArchivePath != "" {
+		problem("sync.blockstore_dir and sync.archive_path are mutually exclusive")
+	}
+	if cfg.Quorum < 0 || cfg.Quorum > len(cfg.RPCEndpoints) {
+		problem("sync.quorum: must be between 0 and the number of rpc endpoints (%d), got %d", len(cfg.RPCEndpoints), cfg.Quorum)
+	}
+	if cfg.RPCSyncWindow < 1 {
+		problem("sync.rpc_sync_window: must be positive, got %d", cfg.RPCSyncWindow)
+	}
+	if cfg.WSPingInterval <= 0 {
+		problem("sync.ws_ping_interval: must be positive, got %s", cfg.WSPingInterval)
+	}
+	if cfg.StallTimeout < 0 {
+		problem("sync.stall_timeout: must not be negative, got %s", cfg.StallTimeout)
+	}
+
+	if cfg.HaltHeight < 0 {
+		problem("sync.halt_height: must not be negative, got %d", cfg.HaltHeight)
+	}
+	if cfg.HaltTime < 0 {
+		problem("sync.halt_time: must not be negative, got %d", cfg.HaltTime)
+	}
+
+	if err := mantlemint.ResultsHashPolicy(cfg.ResultsHashPolicy).Validate(); err != nil {
+		problem("verify.results_hash_policy: %v", err)
+	}
+	if cfg.VerifyBlockResults {
+		if err := validateURL(cfg.VerifyRPCEndpoint, "http", "https"); err != nil {
+			problem("verify.rpc_endpoint: %v", err)
+		}
+	}
+
+	if cfg.MantlemintDB == "" {
+		problem("storage.mantlemint_db is required (MANTLEMINT_DB)")
+	}
+	if cfg.IndexerDB == "" {
+		problem("storage.indexer_db is required (INDEXER_DB)")
+	}
+	switch cfg.DBBackend {
+	case DBBackendGoLevelDB, DBBackendPebble, DBBackendMemDB:
+	default:
+		problem("storage.backend: must be one of %s, %s, %s, got %q", DBBackendGoLevelDB, DBBackendPebble, DBBackendMemDB, cfg.DBBackend)
+	}
+	if cfg.RollbackJournalSize < 0 {
+		problem("storage.rollback_journal_size: must not be negative, got %d", cfg.RollbackJournalSize)
+	}
+
+	if cfg.PruningKeepRecent < 0 {
+		problem("pruning.keep_recent: must not be negative, got %d", cfg.PruningKeepRecent)
+	} else if cfg.PruningKeepRecent > 0 && cfg.PruningKeepRecent <= max(cfg.RollbackJournalSize, 1) {
+		// heights a rollback returns to must stay queryable
+		problem("pruning.keep_recent: must be greater than storage.rollback_journal_size (%d) and 1, got %d", cfg.RollbackJournalSize, cfg.PruningKeepRecent)
+	}
+	if cfg.PruningKeepEvery < 0 {
+		problem("pruning.keep_every: must not be negative, got %d", cfg.PruningKeepEvery)
+	}
+	if cfg.PruningKeepRecent > 0 && cfg.PruningInterval <= 0 {
+		problem("pruning.interval: must be positive, got %s", cfg.PruningInterval)
+	}
+
+	if cfg.TieringColdDir != "" {
+		if cfg.DBBackend == DBBackendMemDB {
+			problem("tiering.cold_dir: can't be used with storage.backend %s", DBBackendMemDB)
+		}
+		if cfg.PruningKeepRecent > 0 {
+			problem("tiering.cold_dir: can't be combined with pruning.keep_recent")
+		}
+		if cfg.TieringPartitionSize < 1 {
+			problem("tiering.partition_size: must be positive, got %d", cfg.TieringPartitionSize)
+		}
+		if cfg.TieringKeepHot <= cfg.RollbackJournalSize {
+			// heights a rollback returns to must stay in mantlemint db
+			problem("tiering.keep_hot: must be greater than storage.rollback_journal_size (%d), got %d", cfg.RollbackJournalSize, cfg.TieringKeepHot)
+		}
+		if cfg.TieringInterval <= 0 {
+			problem("tiering.interval: must be positive, got %s", cfg.TieringInterval)
+		}
+	}
+
+	if cfg.CacheSize < 1 {
+		problem("cache.latest_size: must be positive, got %d", cfg.CacheSize)
+	}
+	if cfg.ArchivalCacheSize < 1 {
+		problem("cache.archival_size: must be positive, got %d", cfg.ArchivalCacheSize)
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid configuration:\n  - %s", strings.Join(problems, "\n  - "))
+}
+
+func isKnownApp(name string) bool {
+	return len(Apps) == 0 || slices.Contains(Apps, name)
+}
+
+func validateURL(rawURL string, schemes ...string) error {
+	if rawURL == "" {
+		return fmt.Errorf("is required")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	for _, scheme := range schemes {
+		if u.Scheme == scheme && u.Host != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("expected %s://host[:port], got %q", strings.Join(schemes, "|"), rawURL)
+}
@@ -0,0 +1,129 @@
+package heleveldb
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	dbm "github.com/cometbft/cometbft-db"
+	"github.com/terra-money/mantlemint/db/hld"
+)
+
+// ColdConfig moves history out of the db into LevelDB partitions of PartitionSize heights each,
+// under Dir; versions read by the last KeepHot heights stay in the db
+type ColdConfig struct {
+	Dir           string
+	Name          string
+	PartitionSize int64
+	KeepHot       int64
+}
+
+// coldStore holds partitions of height-suffixed entries, with the same keys as in the db,
+// opening each only once it is needed
+type coldStore struct {
+	config *ColdConfig
+
+	mtx        *sync.Mutex
+	partitions map[int64]dbm.DB
+	// indexes of partitions on disk, whether open or not
+	existing map[int64]bool
+}
+
+func newColdStore(config *ColdConfig) (*coldStore, error) {
+	if config.PartitionSize <= 0 {
+		return nil, fmt.Errorf("cold partition size must be positive, got %d", config.PartitionSize)
+	}
+	if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(config.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &coldStore{
+		config:     config,
+		mtx:        new(sync.Mutex),
+		partitions: map[int64]dbm.DB{},
+		existing:   map[int64]bool{},
+	}
+	for _, entry := range entries {
+		var start int64
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), config.Name+".") {
+			continue
+		}
+		if _, err := fmt.Sscanf(strings.TrimPrefix(entry.Name(), config.Name+"."), "%d.db", &start); err != nil {
+			continue
+		}
+		c.existing[c.partitionOf(start)] = true
+	}
+	return c, nil
+}
+
+// partitionOf returns the index of the partition holding versions at height
+func (c *coldStore) partitionOf(height int64) int64 {
+	return hld.Height(height).Cluster(c.config.PartitionSize).ToInt64()
+}
+
+func (c *coldStore) partitionName(index int64) string {
+	return fmt.Sprintf("%s.%012d", c.config.Name, index*c.config.PartitionSize)
+}
+
+// partition returns partition index, or nil if it doesn't exist and create is false
+func (c *coldStore) partition(index int64, create bool) (dbm.DB, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if db, ok := c.partitions[index]; ok {
+		return db, nil
+	}
+	if !c.existing[index] && !create {
+		return nil, nil
+	}
+
+	db, err := dbm.NewGoLevelDB(c.partitionName(index), c.config.Dir)
+	if err != nil {
+		return nil, err
+	}
+	c.partitions[index] = db
+	c.existing[index] = true
+	return db, nil
+}
+
+// all opens and returns every partition, oldest first
+func (c *coldStore) all() ([]dbm.DB, error) {
+	c.mtx.Lock()
+	indexes := make([]int64, 0, len(c.existing))
+	for index := range c.existing {
+		indexes = append(indexes, index)
+	}
+	c.mtx.Unlock()
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+
+	partitions := make([]dbm.DB, 0, len(indexes))
+	for _, index := range indexes {
+		db, err := c.partition(index, false)
+		if err != nil {
+			return nil, err
+		}
+		partitions = append(partitions, db)
+	}
+	return partitions, nil
+}
+
+func (c *coldStore) close() error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	var closeErr error
+	for index, db := range c.partitions {
+		if err := db.Close(); err != nil {
+			closeErr = fmt.Errorf("%s: %w", filepath.Join(c.config.Dir, c.partitionName(index)), err)
+		}
+		delete(c.partitions, index)
+	}
+	return closeErr
+}
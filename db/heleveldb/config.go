@@ -4,4 +4,11 @@ type DriverConfig struct {
 	Name string
 	Dir  string
 	Mode int
+
+	// RollbackJournalSize is the number of recent heights to keep undo records for;
+	// 0 disables the rollback journal
+	RollbackJournalSize int64
+
+	// Cold, if set, lets MoveToCold move history out of the db
+	Cold *ColdConfig
 }
@@ -14,9 +14,10 @@ var (
 )
 
 type LevelBatch struct {
-	height int64
-	batch  *rollbackable.RollbackableBatch
-	mode   int
+	height  int64
+	batch   *rollbackable.RollbackableBatch
+	mode    int
+	journal *rollbackable.Journal
 }
 
 func (b *LevelBatch) keyBytesWithHeight(key []byte) []byte {
@@ -24,11 +25,16 @@ func (b *LevelBatch) keyBytesWithHeight(key []byte) []byte {
 }
 
 func NewLevelDBBatch(atHeight int64, driver *Driver) *LevelBatch {
-	return &LevelBatch{
-		height: atHeight,
-		batch:  rollbackable.NewRollbackableBatch(driver.session),
-		mode:   driver.mode,
+	batch := &LevelBatch{
+		height:  atHeight,
+		batch:   rollbackable.NewRollbackableBatch(driver.session),
+		mode:    driver.mode,
+		journal: driver.journal,
 	}
+	if driver.journalSuspended {
+		batch.journal = nil
+	}
+	return batch
 }
 
 func (b *LevelBatch) Set(key, value []byte) error {
@@ -63,13 +69,28 @@ func (b *LevelBatch) Delete(key []byte) error {
 }
 
 func (b *LevelBatch) Write() error {
+	if err := b.appendJournal(); err != nil {
+		return err
+	}
 	return b.batch.Write()
 }
 
 func (b *LevelBatch) WriteSync() error {
+	if err := b.appendJournal(); err != nil {
+		return err
+	}
 	return b.batch.WriteSync()
 }
 
+// appendJournal puts undo records into the underlying batch,
+// bypassing backup as the journal entry itself need not be reverted
+func (b *LevelBatch) appendJournal() error {
+	if b.journal == nil {
+		return nil
+	}
+	return b.journal.Append(b.batch.Batch, b.height, b.batch.Records)
+}
+
 func (b *LevelBatch) Close() error {
 	return b.batch.Close()
 }
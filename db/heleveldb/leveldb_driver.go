@@ -3,15 +3,29 @@ package heleveldb
 import (
 	"fmt"
 	"math"
+	"sync"
 
 	dbm "github.com/cometbft/cometbft-db"
 	"github.com/terra-money/mantlemint/db/hld"
+	"github.com/terra-money/mantlemint/db/rollbackable"
 	"github.com/terra-money/mantlemint/lib"
 )
 
 type Driver struct {
-	session *dbm.GoLevelDB
+	session dbm.DB
 	mode    int
+	journal *rollbackable.Journal
+	// batches created while set are not journaled
+	journalSuspended bool
+
+	pruneMtx *sync.RWMutex
+	pruned   pruningState
+	// floor of the last complete Prune; only touched by Prune
+	prunedTo int64
+
+	cold    *coldStore
+	tierMtx *sync.RWMutex
+	tiered  tieringState
 }
 
 func NewLevelDBDriver(config *DriverConfig) (*Driver, error) {
@@ -19,11 +33,79 @@ func NewLevelDBDriver(config *DriverConfig) (*Driver, error) {
 	if err != nil {
 		return nil, err
 	}
+	return NewDriver(ldb, config), nil
+}
+
+// NewDriver lays out height-versioned keys over session, a db of any backend;
+// Name and Dir of config are left to whoever opened session
+func NewDriver(session dbm.DB, config *DriverConfig) *Driver {
+	var journal *rollbackable.Journal
+	if config.RollbackJournalSize > 0 {
+		journal = rollbackable.NewJournal(session, cRollbackJournalPrefix, config.RollbackJournalSize)
+	}
+
+	pruned, err := loadPruningState(session)
+	if err != nil {
+		panic(err)
+	}
+
+	tiered, err := loadTieringState(session)
+	if err != nil {
+		panic(err)
+	}
+	var cold *coldStore
+	if config.Cold != nil {
+		if tiered.PartitionSize > 0 && tiered.PartitionSize != config.Cold.PartitionSize {
+			panic(fmt.Errorf("cold partitions are of %d heights, can't change to %d", tiered.PartitionSize, config.Cold.PartitionSize))
+		}
+		if cold, err = newColdStore(config.Cold); err != nil {
+			panic(err)
+		}
+	} else if tiered.To > 0 {
+		panic(fmt.Errorf("history below height %d was moved to cold partitions, but cold storage is not configured", tiered.To))
+	}
 
 	return &Driver{
-		session: ldb,
-		mode:    config.Mode,
-	}, nil
+		session:  session,
+		mode:     config.Mode,
+		journal:  journal,
+		pruneMtx: new(sync.RWMutex),
+		pruned:   pruned,
+		cold:     cold,
+		tierMtx:  new(sync.RWMutex),
+		tiered:   tiered,
+	}
+}
+
+// RollbackTo reverts every write made above toHeight using the rollback journal.
+// As the tendermint state store and block store live in the same db,
+// they are reverted along with the app state.
+func (d *Driver) RollbackTo(toHeight int64) error {
+	if d.journal == nil {
+		return fmt.Errorf("rollback journal is disabled")
+	}
+	return d.journal.Rewind(toHeight)
+}
+
+// SuspendJournal keeps batches created until resume is called out of the rollback journal;
+// for writes that aren't blocks, e.g. genesis, which no rollback should revert
+func (d *Driver) SuspendJournal() (resume func()) {
+	d.journalSuspended = true
+	return func() { d.journalSuspended = false }
+}
+
+// RollbackRange returns the range of heights the rollback journal can revert.
+func (d *Driver) RollbackRange() (int64, int64, error) {
+	if d.journal == nil {
+		return 0, 0, nil
+	}
+	return d.journal.Range()
+}
+
+// Session returns the underlying db with keys of every height, journal included;
+// used to copy the db as a whole, along with ColdPartitions if history was moved out of it
+func (d *Driver) Session() dbm.DB {
+	return d.session
 }
 
 func (d *Driver) newInnerIterator(requestHeight int64, pdb *dbm.PrefixDB) (dbm.Iterator, error) {
@@ -40,6 +122,9 @@ func (d *Driver) Get(maxHeight int64, key []byte) ([]byte, error) {
 	if maxHeight == 0 {
 		return d.session.Get(prefixCurrentDataKey(key))
 	}
+	if err := d.CheckHeight(maxHeight); err != nil {
+		return nil, err
+	}
 	requestHeight := hld.Height(maxHeight).CurrentOrLatest().ToInt64()
 	requestHeightMin := hld.Height(0).CurrentOrNever().ToInt64()
 
@@ -48,17 +133,11 @@ func (d *Driver) Get(maxHeight int64, key []byte) ([]byte, error) {
 		return nil, fmt.Errorf("invalid height")
 	}
 
-	pdb := dbm.NewPrefixDB(d.session, prefixDataWithHeightKey(key))
-
-	iter, _ := d.newInnerIterator(requestHeight, pdb)
-	defer iter.Close()
-
-	// in tm-db@v0.6.4, key not found is NOT an error
-	if !iter.Valid() {
-		return nil, nil
+	value, err := d.versionAt(requestHeight, key)
+	if err != nil || value == nil {
+		return nil, err
 	}
 
-	value := iter.Value()
 	deleted := value[0]
 	if deleted == 1 {
 		return nil, nil
@@ -74,6 +153,9 @@ func (d *Driver) Has(maxHeight int64, key []byte) (bool, error) {
 	if maxHeight == 0 {
 		return d.session.Has(prefixCurrentDataKey(key))
 	}
+	if err := d.CheckHeight(maxHeight); err != nil {
+		return false, err
+	}
 	requestHeight := hld.Height(maxHeight).CurrentOrLatest().ToInt64()
 	requestHeightMin := hld.Height(0).CurrentOrNever().ToInt64()
 
@@ -82,18 +164,12 @@ func (d *Driver) Has(maxHeight int64, key []byte) (bool, error) {
 		return false, fmt.Errorf("invalid height")
 	}
 
-	pdb := dbm.NewPrefixDB(d.session, prefixDataWithHeightKey(key))
-
-	iter, _ := d.newInnerIterator(requestHeight, pdb)
-	defer iter.Close()
-
-	// in tm-db@v0.6.4, key not found is NOT an error
-	if !iter.Valid() {
-		return false, nil
+	value, err := d.versionAt(requestHeight, key)
+	if err != nil || value == nil {
+		return false, err
 	}
 
-	deleted := iter.Value()[0]
-
+	deleted := value[0]
 	if deleted == 1 {
 		return false, nil
 	} else {
@@ -125,6 +201,9 @@ func (d *Driver) Iterator(maxHeight int64, start, end []byte) (hld.HeightLimitEn
 		pdb := dbm.NewPrefixDB(d.session, cCurrentDataPrefix)
 		return pdb.Iterator(start, end)
 	}
+	if err := d.CheckHeight(maxHeight); err != nil {
+		return nil, err
+	}
 	return NewLevelDBIterator(d, maxHeight, start, end)
 }
 
@@ -133,11 +212,17 @@ func (d *Driver) ReverseIterator(maxHeight int64, start, end []byte) (hld.Height
 		pdb := dbm.NewPrefixDB(d.session, cCurrentDataPrefix)
 		return pdb.ReverseIterator(start, end)
 	}
+	if err := d.CheckHeight(maxHeight); err != nil {
+		return nil, err
+	}
 	return NewLevelDBReverseIterator(d, maxHeight, start, end)
 }
 
 func (d *Driver) Close() error {
 	d.session.Close()
+	if d.cold != nil {
+		return d.cold.close()
+	}
 	return nil
 }
 
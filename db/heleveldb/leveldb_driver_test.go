@@ -0,0 +1,22 @@
+package heleveldb
+
+import (
+	"testing"
+
+	"github.com/terra-money/mantlemint/db/hld"
+	"github.com/terra-money/mantlemint/db/hld/hldtest"
+)
+
+func TestLevelDBDriver(t *testing.T) {
+	for name, mode := range map[string]int{"asc": DriverModeKeySuffixAsc, "desc": DriverModeKeySuffixDesc} {
+		t.Run(name, func(t *testing.T) {
+			hldtest.RunDriverTests(t, func(t *testing.T) hld.HeightLimitEnabledDB {
+				driver, err := NewLevelDBDriver(&DriverConfig{Name: "test", Dir: t.TempDir(), Mode: mode})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return driver
+			})
+		})
+	}
+}
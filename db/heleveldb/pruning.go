@@ -0,0 +1,207 @@
+package heleveldb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	dbm "github.com/cometbft/cometbft-db"
+	"github.com/terra-money/mantlemint/lib"
+)
+
+// pruneBatchSize is how many deletes go into a single db batch while pruning
+const pruneBatchSize = 10000
+
+var ErrHeightPruned = errors.New("height is pruned")
+
+// PruningPolicy decides which heights stay queryable:
+// the last KeepRecent heights, plus every KeepEvery-th height below them.
+// KeepRecent of 0 keeps everything; KeepEvery of 0 keeps nothing below the recent heights.
+type PruningPolicy struct {
+	KeepRecent int64
+	KeepEvery  int64
+}
+
+func (p PruningPolicy) Enabled() bool {
+	return p.KeepRecent > 0
+}
+
+// pruningState is what has been pruned so far: heights below Floor are gone,
+// except for multiples of KeepEvery
+type pruningState struct {
+	Floor     int64
+	KeepEvery int64
+}
+
+func (s pruningState) retains(height int64) bool {
+	return height >= s.Floor || (s.KeepEvery > 0 && height%s.KeepEvery == 0)
+}
+
+// readsAny tells whether any height within from..to is retained
+func (s pruningState) readsAny(from, to int64) bool {
+	if to >= s.Floor {
+		return true
+	}
+	if s.KeepEvery <= 0 {
+		return false
+	}
+	// the first multiple of KeepEvery not below from
+	firstKept := (from + s.KeepEvery - 1) / s.KeepEvery * s.KeepEvery
+	return firstKept <= to
+}
+
+func (s pruningState) encode() []byte {
+	return lib.ConcatBytes(lib.UintToBigEndian(uint64(s.Floor)), lib.UintToBigEndian(uint64(s.KeepEvery)))
+}
+
+func loadPruningState(session dbm.DB) (pruningState, error) {
+	data, err := session.Get(cPruningStateKey)
+	if err != nil || data == nil {
+		return pruningState{}, err
+	}
+	if len(data) != 16 {
+		return pruningState{}, fmt.Errorf("corrupted pruning state of %d bytes", len(data))
+	}
+	return pruningState{
+		Floor:     int64(lib.BigEndianToUint(data[:8])),
+		KeepEvery: int64(lib.BigEndianToUint(data[8:])),
+	}, nil
+}
+
+// PruneStats is the outcome of a single Prune
+type PruneStats struct {
+	Floor   int64
+	Keys    int64
+	Deleted int64
+}
+
+// CheckHeight returns ErrHeightPruned if height is no longer queryable; 0 is the latest height
+func (d *Driver) CheckHeight(height int64) error {
+	d.pruneMtx.RLock()
+	state := d.pruned
+	d.pruneMtx.RUnlock()
+
+	if height <= 0 || state.retains(height) {
+		return nil
+	}
+	if state.KeepEvery > 0 {
+		return fmt.Errorf("%w: %d; heights below %d are only kept every %d blocks", ErrHeightPruned, height, state.Floor, state.KeepEvery)
+	}
+	return fmt.Errorf("%w: %d; the lowest height kept is %d", ErrHeightPruned, height, state.Floor)
+}
+
+// PrunedFloor returns the lowest height kept in full, 0 if never pruned
+func (d *Driver) PrunedFloor() int64 {
+	d.pruneMtx.RLock()
+	defer d.pruneMtx.RUnlock()
+	return d.pruned.Floor
+}
+
+// Prune deletes versions of keys that no height retained by policy reads anymore,
+// latestHeight being the last committed height. Reads of retained heights are unaffected.
+// The newest version of a key is never deleted, so Prune may run while blocks are written;
+// Prune itself must not run concurrently; an interrupted Prune picks up again on the next run.
+func (d *Driver) Prune(ctx context.Context, policy PruningPolicy, latestHeight int64) (PruneStats, error) {
+	if !policy.Enabled() {
+		return PruneStats{}, nil
+	}
+
+	d.pruneMtx.RLock()
+	state := d.pruned
+	d.pruneMtx.RUnlock()
+
+	d.tierMtx.RLock()
+	tieredTo := d.tiered.To
+	d.tierMtx.RUnlock()
+	if tieredTo > 0 {
+		return PruneStats{}, fmt.Errorf("history below height %d is in cold partitions; tiered dbs can't be pruned", tieredTo)
+	}
+
+	if state.Floor > 0 && state.KeepEvery != policy.KeepEvery {
+		return PruneStats{}, fmt.Errorf("pruning keep_every can't change once pruned; it was %d", state.KeepEvery)
+	}
+	// heights below the floor persisted are gone already; finish an interrupted run, if any
+	floor := max(latestHeight-policy.KeepRecent+1, state.Floor)
+	if floor < 1 || floor == d.prunedTo {
+		return PruneStats{Floor: state.Floor}, nil
+	}
+
+	// queries below the new floor are rejected from here on, before anything is deleted
+	if floor > state.Floor {
+		state = pruningState{Floor: floor, KeepEvery: policy.KeepEvery}
+		if err := d.session.SetSync(cPruningStateKey, state.encode()); err != nil {
+			return PruneStats{}, err
+		}
+		d.pruneMtx.Lock()
+		d.pruned = state
+		d.pruneMtx.Unlock()
+	}
+
+	stats := PruneStats{Floor: floor}
+	keys, err := dbm.NewPrefixDB(d.session, cKeysForIteratorPrefix).Iterator(nil, nil)
+	if err != nil {
+		return stats, err
+	}
+	defer keys.Close()
+
+	batch := d.session.NewBatch()
+	defer func() { batch.Close() }()
+	pending := 0
+
+	for ; keys.Valid(); keys.Next() {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		superseded, err := d.supersededVersions(state, keys.Key())
+		if err != nil {
+			return stats, err
+		}
+		stats.Keys++
+
+		for _, versionKey := range superseded {
+			if err := batch.Delete(versionKey); err != nil {
+				return stats, err
+			}
+			stats.Deleted++
+			if pending++; pending < pruneBatchSize {
+				continue
+			}
+
+			if err := batch.Write(); err != nil {
+				return stats, err
+			}
+			batch.Close()
+			batch = d.session.NewBatch()
+			pending = 0
+		}
+	}
+	if err := keys.Error(); err != nil {
+		return stats, err
+	}
+	if err := batch.WriteSync(); err != nil {
+		return stats, err
+	}
+	d.prunedTo = floor
+	return stats, nil
+}
+
+// supersededVersions returns the db keys of versions of key no retained height reads:
+// a version is read by heights from its own up to right before the next version.
+// Tombstones with nothing kept below them read the same as no version at all.
+func (d *Driver) supersededVersions(state pruningState, key []byte) ([][]byte, error) {
+	versions, err := d.versions(d.session, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var superseded [][]byte
+	keptBelow := false
+	for i := 0; i < len(versions)-1; i++ {
+		if state.readsAny(versions[i].height, versions[i+1].height-1) && (keptBelow || !versions[i].deleted()) {
+			keptBelow = true
+			continue
+		}
+		superseded = append(superseded, versions[i].key)
+	}
+	return superseded, nil
+}
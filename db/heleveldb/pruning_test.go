@@ -0,0 +1,115 @@
+package heleveldb
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrune(t *testing.T) {
+	for name, mode := range map[string]int{"asc": DriverModeKeySuffixAsc, "desc": DriverModeKeySuffixDesc} {
+		t.Run(name, func(t *testing.T) {
+			driver, err := NewLevelDBDriver(&DriverConfig{Name: "test", Dir: t.TempDir(), Mode: mode})
+			assert.Nil(t, err)
+			defer driver.Close()
+
+			// "a" changes every height, "b" every third, "c" is deleted and set back in turns,
+			// "ab" shares the prefix of "a"
+			const latest = 30
+			for height := int64(1); height <= latest; height++ {
+				batch := driver.NewBatch(height)
+				assert.Nil(t, batch.Set([]byte("a"), []byte(fmt.Sprint(height))))
+				assert.Nil(t, batch.Set([]byte("ab"), []byte(fmt.Sprint(height))))
+				if height%3 == 0 {
+					assert.Nil(t, batch.Set([]byte("b"), []byte(fmt.Sprint(height))))
+				}
+				if height%4 == 0 {
+					assert.Nil(t, batch.Delete([]byte("c")))
+				} else if height%4 == 2 {
+					assert.Nil(t, batch.Set([]byte("c"), []byte(fmt.Sprint(height))))
+				}
+				assert.Nil(t, batch.Write())
+				batch.Close()
+			}
+
+			keys := []string{"a", "ab", "b", "c"}
+			read := func(height int64) map[string]string {
+				values := map[string]string{}
+				for _, key := range keys {
+					value, err := driver.Get(height, []byte(key))
+					assert.Nil(t, err)
+					if value != nil {
+						values[key] = string(value)
+					}
+				}
+				return values
+			}
+			before := map[int64]map[string]string{}
+			for height := int64(1); height <= latest; height++ {
+				before[height] = read(height)
+			}
+
+			policy := PruningPolicy{KeepRecent: 10, KeepEvery: 7}
+			stats, err := driver.Prune(context.Background(), policy, latest)
+			assert.Nil(t, err)
+			assert.Equal(t, int64(21), stats.Floor)
+			assert.Equal(t, int64(4), stats.Keys)
+			assert.True(t, stats.Deleted > 0)
+
+			for height := int64(1); height <= latest; height++ {
+				if height >= 21 || height%7 == 0 {
+					assert.Nil(t, driver.CheckHeight(height))
+					assert.Equal(t, before[height], read(height), "height %d", height)
+
+					it, err := driver.Iterator(height, nil, nil)
+					assert.Nil(t, err)
+					iterated := map[string]string{}
+					for ; it.Valid(); it.Next() {
+						iterated[string(it.Key())] = string(it.Value())
+					}
+					it.Close()
+					assert.Equal(t, before[height], iterated, "height %d", height)
+				} else {
+					assert.ErrorIs(t, driver.CheckHeight(height), ErrHeightPruned)
+					_, err := driver.Get(height, []byte("a"))
+					assert.ErrorIs(t, err, ErrHeightPruned)
+					_, err = driver.Iterator(height, nil, nil)
+					assert.ErrorIs(t, err, ErrHeightPruned)
+				}
+			}
+
+			// nothing left to prune at the same height; keep_every is fixed once pruned
+			stats, err = driver.Prune(context.Background(), policy, latest)
+			assert.Nil(t, err)
+			assert.Equal(t, int64(0), stats.Deleted)
+			_, err = driver.Prune(context.Background(), PruningPolicy{KeepRecent: 10, KeepEvery: 5}, latest+1)
+			assert.ErrorContains(t, err, "keep_every")
+		})
+	}
+}
+
+func TestPruneFloorPersists(t *testing.T) {
+	dir := t.TempDir()
+	driver, err := NewLevelDBDriver(&DriverConfig{Name: "test", Dir: dir, Mode: DriverModeKeySuffixDesc})
+	assert.Nil(t, err)
+	for height := int64(1); height <= 5; height++ {
+		batch := driver.NewBatch(height)
+		assert.Nil(t, batch.Set([]byte("a"), []byte(fmt.Sprint(height))))
+		assert.Nil(t, batch.Write())
+		batch.Close()
+	}
+	_, err = driver.Prune(context.Background(), PruningPolicy{KeepRecent: 2}, 5)
+	assert.Nil(t, err)
+	assert.Nil(t, driver.Close())
+
+	driver, err = NewLevelDBDriver(&DriverConfig{Name: "test", Dir: dir, Mode: DriverModeKeySuffixDesc})
+	assert.Nil(t, err)
+	defer driver.Close()
+	assert.Equal(t, int64(4), driver.PrunedFloor())
+	assert.ErrorContains(t, driver.CheckHeight(3), "the lowest height kept is 4")
+	value, err := driver.Get(4, []byte("a"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("4"), value)
+}
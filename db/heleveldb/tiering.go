@@ -0,0 +1,269 @@
+package heleveldb
+
+import (
+	"context"
+	"fmt"
+
+	dbm "github.com/cometbft/cometbft-db"
+	"github.com/terra-money/mantlemint/lib"
+)
+
+// tierBatchSize is how many versions are moved to cold partitions in a single round of batches
+const tierBatchSize = 10000
+
+// tieringState is how far history has moved to cold partitions: versions only read below To
+// may be in cold partitions rather than in the db, and versions below Moved are all in cold partitions
+type tieringState struct {
+	To            int64
+	Moved         int64
+	PartitionSize int64
+}
+
+func (s tieringState) encode() []byte {
+	return lib.ConcatBytes(
+		lib.UintToBigEndian(uint64(s.To)),
+		lib.UintToBigEndian(uint64(s.Moved)),
+		lib.UintToBigEndian(uint64(s.PartitionSize)),
+	)
+}
+
+func loadTieringState(session dbm.DB) (tieringState, error) {
+	data, err := session.Get(cTieringStateKey)
+	if err != nil || data == nil {
+		return tieringState{}, err
+	}
+	if len(data) != 24 {
+		return tieringState{}, fmt.Errorf("corrupted tiering state of %d bytes", len(data))
+	}
+	return tieringState{
+		To:            int64(lib.BigEndianToUint(data[:8])),
+		Moved:         int64(lib.BigEndianToUint(data[8:16])),
+		PartitionSize: int64(lib.BigEndianToUint(data[16:])),
+	}, nil
+}
+
+func (d *Driver) saveTieringState(state tieringState) error {
+	if err := d.session.SetSync(cTieringStateKey, state.encode()); err != nil {
+		return err
+	}
+	d.tierMtx.Lock()
+	d.tiered = state
+	d.tierMtx.Unlock()
+	return nil
+}
+
+// ResetTiering forgets that history of the db in session was moved to cold partitions;
+// for a db holding its whole history again, e.g. restored from a snapshot
+func ResetTiering(session dbm.DB) error {
+	return session.DeleteSync(cTieringStateKey)
+}
+
+// TierStats is the outcome of a single MoveToCold
+type TierStats struct {
+	To      int64
+	Keys    int64
+	Copied  int64
+	Deleted int64
+}
+
+// MoveToCold moves versions of keys not read by the last KeepHot heights of latestHeight,
+// in whole partitions, out of the db into cold partitions.
+// Like Prune, it may run while blocks are written but must not run concurrently with itself.
+func (d *Driver) MoveToCold(ctx context.Context, latestHeight int64) (TierStats, error) {
+	if d.cold == nil {
+		return TierStats{}, nil
+	}
+	if floor := d.PrunedFloor(); floor > 0 {
+		return TierStats{}, fmt.Errorf("history below height %d is pruned; pruned dbs can't be tiered", floor)
+	}
+
+	d.tierMtx.RLock()
+	state := d.tiered
+	d.tierMtx.RUnlock()
+
+	size := d.cold.config.PartitionSize
+	to := max((latestHeight-d.cold.config.KeepHot+1)/size*size, state.To)
+	if to <= 0 || to == state.Moved {
+		return TierStats{To: state.To}, nil
+	}
+
+	// reads below the new height consult cold partitions from here on, before anything is moved
+	if to > state.To {
+		state.To = to
+		state.PartitionSize = size
+		if err := d.saveTieringState(state); err != nil {
+			return TierStats{}, err
+		}
+	}
+
+	stats := TierStats{To: to}
+	keys, err := dbm.NewPrefixDB(d.session, cKeysForIteratorPrefix).Iterator(nil, nil)
+	if err != nil {
+		return stats, err
+	}
+	defer keys.Close()
+
+	coldBatches := map[int64]dbm.Batch{}
+	hotBatch := d.session.NewBatch()
+	closeBatches := func() {
+		for _, batch := range coldBatches {
+			batch.Close()
+		}
+		hotBatch.Close()
+	}
+	defer func() { closeBatches() }()
+
+	// versions are deleted from the db only once they are safely in cold partitions
+	pending := 0
+	flush := func() error {
+		for _, batch := range coldBatches {
+			if err := batch.WriteSync(); err != nil {
+				return err
+			}
+		}
+		if err := hotBatch.WriteSync(); err != nil {
+			return err
+		}
+		closeBatches()
+		coldBatches = map[int64]dbm.Batch{}
+		hotBatch = d.session.NewBatch()
+		pending = 0
+		return nil
+	}
+
+	for ; keys.Valid(); keys.Next() {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		versions, err := d.versions(d.session, keys.Key())
+		if err != nil {
+			return stats, err
+		}
+		stats.Keys++
+
+		// the first version of a key bounds the partitions searched for it; see versionAt.
+		// Once versions are moved, the oldest one left in the db is no longer the first
+		if len(versions) > 0 && versions[0].height < to {
+			firstHeightKey := prefixFirstHeightKey(keys.Key())
+			marked, err := d.session.Has(firstHeightKey)
+			if err != nil {
+				return stats, err
+			}
+			if !marked {
+				if err := hotBatch.Set(firstHeightKey, lib.UintToBigEndian(uint64(versions[0].height))); err != nil {
+					return stats, err
+				}
+				pending++
+			}
+		}
+
+		for i, version := range versions {
+			if version.height >= to {
+				break
+			}
+			// versions below Moved have been copied by an earlier run
+			if version.height >= state.Moved {
+				index := d.cold.partitionOf(version.height)
+				batch, ok := coldBatches[index]
+				if !ok {
+					partition, err := d.cold.partition(index, true)
+					if err != nil {
+						return stats, err
+					}
+					batch = partition.NewBatch()
+					coldBatches[index] = batch
+				}
+				if err := batch.Set(version.key, version.value); err != nil {
+					return stats, err
+				}
+				stats.Copied++
+				pending++
+			}
+			// the version read at to stays in the db, so that heights from to on never need cold partitions
+			if i+1 < len(versions) && versions[i+1].height <= to {
+				if err := hotBatch.Delete(version.key); err != nil {
+					return stats, err
+				}
+				stats.Deleted++
+				pending++
+			}
+		}
+
+		if pending >= tierBatchSize {
+			if err := flush(); err != nil {
+				return stats, err
+			}
+		}
+	}
+	if err := keys.Error(); err != nil {
+		return stats, err
+	}
+	if err := flush(); err != nil {
+		return stats, err
+	}
+
+	state.Moved = to
+	return stats, d.saveTieringState(state)
+}
+
+// versionAt returns the stored value of the newest version of key at or below height,
+// deleted flag included. Below the tiered height, the version may be in a cold partition;
+// partitions are searched newest first, down to the partition of the version found in the db,
+// or of the first version of key if there is none.
+func (d *Driver) versionAt(height int64, key []byte) ([]byte, error) {
+	value, versionHeight, err := d.newestVersion(d.session, key, height)
+	if err != nil || d.cold == nil {
+		return value, err
+	}
+
+	d.tierMtx.RLock()
+	tieredTo := d.tiered.To
+	d.tierMtx.RUnlock()
+	if height >= tieredTo {
+		return value, nil
+	}
+
+	var lowest int64
+	if value != nil {
+		lowest = d.cold.partitionOf(versionHeight)
+	} else {
+		// keys without a first height marker have no versions in cold partitions
+		first, err := d.session.Get(prefixFirstHeightKey(key))
+		if err != nil || first == nil {
+			return nil, err
+		}
+		firstHeight := int64(lib.BigEndianToUint(first))
+		if firstHeight > height {
+			return nil, nil
+		}
+		lowest = d.cold.partitionOf(firstHeight)
+	}
+	for index := d.cold.partitionOf(height); index >= lowest; index-- {
+		partition, err := d.cold.partition(index, false)
+		if err != nil {
+			return nil, err
+		} else if partition == nil {
+			continue
+		}
+
+		coldValue, coldHeight, err := d.newestVersion(partition, key, height)
+		if err != nil {
+			return nil, err
+		} else if coldValue == nil {
+			continue
+		}
+		if value == nil || coldHeight > versionHeight {
+			return coldValue, nil
+		}
+		break
+	}
+	return value, nil
+}
+
+// ColdPartitions opens and returns every cold partition, oldest first; none if tiering is disabled
+func (d *Driver) ColdPartitions() ([]dbm.DB, error) {
+	if d.cold == nil {
+		return nil, nil
+	}
+	return d.cold.all()
+}
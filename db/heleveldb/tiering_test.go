@@ -0,0 +1,110 @@
+package heleveldb
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMoveToCold(t *testing.T) {
+	for name, mode := range map[string]int{"asc": DriverModeKeySuffixAsc, "desc": DriverModeKeySuffixDesc} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			config := &DriverConfig{
+				Name: "test",
+				Dir:  dir,
+				Mode: mode,
+				Cold: &ColdConfig{Dir: t.TempDir(), Name: "test", PartitionSize: 5, KeepHot: 10},
+			}
+			driver, err := NewLevelDBDriver(config)
+			assert.Nil(t, err)
+
+			// "a" changes every height, "b" every seventh, "c" is deleted and set back in turns,
+			// "d" only exists early on
+			write := func(from, to int64) {
+				for height := from; height <= to; height++ {
+					batch := driver.NewBatch(height)
+					assert.Nil(t, batch.Set([]byte("a"), []byte(fmt.Sprint(height))))
+					if height%7 == 0 {
+						assert.Nil(t, batch.Set([]byte("b"), []byte(fmt.Sprint(height))))
+					}
+					if height%4 == 0 {
+						assert.Nil(t, batch.Delete([]byte("c")))
+					} else if height%4 == 2 {
+						assert.Nil(t, batch.Set([]byte("c"), []byte(fmt.Sprint(height))))
+					}
+					if height == 2 {
+						assert.Nil(t, batch.Set([]byte("d"), []byte("2")))
+					} else if height == 3 {
+						assert.Nil(t, batch.Delete([]byte("d")))
+					}
+					assert.Nil(t, batch.Write())
+					batch.Close()
+				}
+			}
+			read := func(height int64) map[string]string {
+				values := map[string]string{}
+				it, err := driver.Iterator(height, nil, nil)
+				assert.Nil(t, err)
+				defer it.Close()
+				for ; it.Valid(); it.Next() {
+					values[string(it.Key())] = string(it.Value())
+				}
+				return values
+			}
+			readAll := func(latest int64) map[int64]map[string]string {
+				all := map[int64]map[string]string{}
+				for height := int64(1); height <= latest; height++ {
+					all[height] = read(height)
+				}
+				return all
+			}
+
+			write(1, 30)
+			before := readAll(30)
+
+			stats, err := driver.MoveToCold(context.Background(), 30)
+			assert.Nil(t, err)
+			assert.Equal(t, int64(20), stats.To)
+			assert.True(t, stats.Copied > 0)
+			assert.True(t, stats.Deleted > 0)
+			assert.Equal(t, before, readAll(30))
+
+			// nothing left until the next partition is due
+			stats, err = driver.MoveToCold(context.Background(), 33)
+			assert.Nil(t, err)
+			assert.Equal(t, int64(0), stats.Copied)
+
+			// cold partitions are found again after reopening
+			assert.Nil(t, driver.Close())
+			driver, err = NewLevelDBDriver(config)
+			assert.Nil(t, err)
+			defer driver.Close()
+
+			// reads of keys before they first existed don't search cold partitions
+			for _, key := range []string{"b", "none"} {
+				value, err := driver.Get(6, []byte(key))
+				assert.Nil(t, err)
+				assert.Nil(t, value)
+			}
+			assert.Empty(t, driver.cold.partitions)
+			assert.Equal(t, before, readAll(30))
+
+			write(31, 36)
+			before = readAll(36)
+			stats, err = driver.MoveToCold(context.Background(), 36)
+			assert.Nil(t, err)
+			assert.Equal(t, int64(25), stats.To)
+			assert.Equal(t, before, readAll(36))
+
+			partitions, err := driver.ColdPartitions()
+			assert.Nil(t, err)
+			assert.Len(t, partitions, 5)
+
+			_, err = driver.Prune(context.Background(), PruningPolicy{KeepRecent: 10}, 36)
+			assert.ErrorContains(t, err, "can't be pruned")
+		})
+	}
+}
@@ -16,6 +16,10 @@ var (
 	cCurrentDataPrefix     = []byte{0}
 	cKeysForIteratorPrefix = []byte{1}
 	cDataWithHeightPrefix  = []byte{2}
+	cRollbackJournalPrefix = []byte{3}
+	cPruningStateKey       = []byte{4}
+	cTieringStateKey       = []byte{5}
+	cFirstHeightPrefix     = []byte{6}
 )
 
 func prefixCurrentDataKey(key []byte) []byte {
@@ -26,6 +30,10 @@ func prefixKeysForIteratorKey(key []byte) []byte {
 	return append(cKeysForIteratorPrefix, key...)
 }
 
+func prefixFirstHeightKey(key []byte) []byte {
+	return append(cFirstHeightPrefix, key...)
+}
+
 func prefixDataWithHeightKey(key []byte) []byte {
 	result := make([]byte, 0, len(cDataWithHeightPrefix)+len(key))
 	result = append(result, cDataWithHeightPrefix...)
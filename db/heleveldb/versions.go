@@ -0,0 +1,80 @@
+package heleveldb
+
+import (
+	"math"
+
+	dbm "github.com/cometbft/cometbft-db"
+)
+
+// keyVersion is a single height-suffixed entry of a key
+type keyVersion struct {
+	key    []byte
+	height int64
+	value  []byte
+}
+
+func (v keyVersion) deleted() bool {
+	return v.value[0] == 1
+}
+
+// versions returns every version of key in db, oldest first
+func (d *Driver) versions(db dbm.DB, key []byte) ([]keyVersion, error) {
+	prefix := prefixDataWithHeightKey(key)
+	it, err := db.Iterator(prefix, prefixEnd(prefix))
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	var versions []keyVersion
+	for ; it.Valid(); it.Next() {
+		// longer keys sharing the prefix are not versions of key
+		if len(it.Key()) != len(prefix)+8 {
+			continue
+		}
+		versions = append(versions, keyVersion{
+			key:    append([]byte{}, it.Key()...),
+			height: deserializeHeight(d.mode, it.Key()[len(prefix):]),
+			value:  append([]byte{}, it.Value()...),
+		})
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	if d.mode == DriverModeKeySuffixDesc {
+		for i, j := 0, len(versions)-1; i < j; i, j = i+1, j-1 {
+			versions[i], versions[j] = versions[j], versions[i]
+		}
+	}
+	return versions, nil
+}
+
+// newestVersion returns the stored value, deleted flag included, and the height
+// of the newest version of key in db at or below height; nil if there is none
+func (d *Driver) newestVersion(db dbm.DB, key []byte, height int64) ([]byte, int64, error) {
+	pdb := dbm.NewPrefixDB(db, prefixDataWithHeightKey(key))
+
+	iter, err := d.newInnerIterator(height, pdb)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer iter.Close()
+
+	// in tm-db@v0.6.4, key not found is NOT an error
+	if !iter.Valid() {
+		return nil, 0, nil
+	}
+	return iter.Value(), deserializeHeight(d.mode, iter.Key()), nil
+}
+
+// prefixEnd returns the first key past every key with prefix
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte{}, prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < math.MaxUint8 {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package hememdb
+
+import (
+	dbm "github.com/cometbft/cometbft-db"
+)
+
+var _ dbm.Batch = (*memBatch)(nil)
+
+type operation struct {
+	item
+	delete bool
+}
+
+// memBatch applies its operations at once, under the write lock of the db
+type memBatch struct {
+	db  *MemDB
+	ops []operation
+}
+
+func newMemBatch(db *MemDB) *memBatch {
+	return &memBatch{
+		db:  db,
+		ops: []operation{},
+	}
+}
+
+func (b *memBatch) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if b.ops == nil {
+		return errBatchClosed
+	}
+	b.ops = append(b.ops, operation{item: item{key: cp(key), value: cp(value)}})
+	return nil
+}
+
+func (b *memBatch) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if b.ops == nil {
+		return errBatchClosed
+	}
+	b.ops = append(b.ops, operation{item: item{key: cp(key)}, delete: true})
+	return nil
+}
+
+func (b *memBatch) Write() error {
+	if b.ops == nil {
+		return errBatchClosed
+	}
+
+	b.db.mtx.Lock()
+	for _, op := range b.ops {
+		if op.delete {
+			b.db.tree.Delete(op.item)
+		} else {
+			b.db.tree.ReplaceOrInsert(op.item)
+		}
+	}
+	b.db.mtx.Unlock()
+
+	// as with goleveldb, a written batch can't be reused; callers still Close it
+	return b.Close()
+}
+
+func (b *memBatch) WriteSync() error {
+	return b.Write()
+}
+
+func (b *memBatch) Close() error {
+	b.ops = nil
+	return nil
+}
@@ -0,0 +1,153 @@
+package hememdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	dbm "github.com/cometbft/cometbft-db"
+	"github.com/google/btree"
+)
+
+const bTreeDegree = 32
+
+var (
+	errKeyEmpty    = errors.New("key cannot be empty")
+	errValueNil    = errors.New("value cannot be nil")
+	errBatchClosed = errors.New("batch has been written or closed")
+)
+
+var _ dbm.DB = (*MemDB)(nil)
+
+type item struct {
+	key   []byte
+	value []byte
+}
+
+func itemLess(a, b item) bool {
+	return bytes.Compare(a.key, b.key) < 0
+}
+
+// MemDB is an in-memory dbm.DB whose iterators read a snapshot taken when they are opened, as
+// goleveldb's do. Unlike dbm.MemDB, an open iterator holds no lock: heleveldb reads and writes
+// with iterators open, which would deadlock there.
+type MemDB struct {
+	mtx  *sync.RWMutex
+	tree *btree.BTreeG[item]
+}
+
+func NewMemDB() *MemDB {
+	return &MemDB{
+		mtx:  new(sync.RWMutex),
+		tree: btree.NewG(bTreeDegree, itemLess),
+	}
+}
+
+func (m *MemDB) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	found, ok := m.tree.Get(item{key: key})
+	if !ok {
+		return nil, nil
+	}
+	return cp(found.value), nil
+}
+
+func (m *MemDB) Has(key []byte) (bool, error) {
+	value, err := m.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return value != nil, nil
+}
+
+func (m *MemDB) Set(key []byte, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.tree.ReplaceOrInsert(item{key: cp(key), value: cp(value)})
+	return nil
+}
+
+func (m *MemDB) SetSync(key []byte, value []byte) error {
+	return m.Set(key, value)
+}
+
+func (m *MemDB) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.tree.Delete(item{key: key})
+	return nil
+}
+
+func (m *MemDB) DeleteSync(key []byte) error {
+	return m.Delete(key)
+}
+
+func (m *MemDB) Iterator(start, end []byte) (dbm.Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	return newMemIterator(m.snapshot(), start, end, false), nil
+}
+
+func (m *MemDB) ReverseIterator(start, end []byte) (dbm.Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	return newMemIterator(m.snapshot(), start, end, true), nil
+}
+
+// snapshot clones the tree copy-on-write; cloning marks the tree shared, so it takes the write lock
+func (m *MemDB) snapshot() *btree.BTreeG[item] {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.tree.Clone()
+}
+
+func (m *MemDB) NewBatch() dbm.Batch {
+	return newMemBatch(m)
+}
+
+func (m *MemDB) Close() error {
+	return nil
+}
+
+func (m *MemDB) Print() error {
+	m.snapshot().Ascend(func(i item) bool {
+		fmt.Printf("[%X]:\t[%X]\n", i.key, i.value)
+		return true
+	})
+	return nil
+}
+
+func (m *MemDB) Stats() map[string]string {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	return map[string]string{
+		"database.type": "hememdb",
+		"database.size": strconv.Itoa(m.tree.Len()),
+	}
+}
+
+func cp(bz []byte) []byte {
+	ret := make([]byte, len(bz))
+	copy(ret, bz)
+	return ret
+}
@@ -0,0 +1,77 @@
+package hememdb
+
+import (
+	"fmt"
+	"testing"
+
+	dbm "github.com/cometbft/cometbft-db"
+	"github.com/stretchr/testify/assert"
+)
+
+func keys(t *testing.T, it dbm.Iterator, err error) []string {
+	assert.Nil(t, err)
+	defer it.Close()
+
+	var keys []string
+	for ; it.Valid(); it.Next() {
+		keys = append(keys, string(it.Key()))
+	}
+	return keys
+}
+
+func TestMemDBIterator(t *testing.T) {
+	db := NewMemDB()
+
+	// spans several chunks
+	var all []string
+	for i := 0; i < 3*memIteratorChunkSize; i++ {
+		key := fmt.Sprintf("%04d", i)
+		all = append(all, key)
+		assert.Nil(t, db.Set([]byte(key), []byte(key)))
+	}
+	reversed := make([]string, len(all))
+	for i, key := range all {
+		reversed[len(all)-1-i] = key
+	}
+
+	it, err := db.Iterator(nil, nil)
+	assert.Equal(t, all, keys(t, it, err))
+	it, err = db.ReverseIterator(nil, nil)
+	assert.Equal(t, reversed, keys(t, it, err))
+
+	// start is inclusive, end exclusive
+	it, err = db.Iterator([]byte("0010"), []byte("0150"))
+	assert.Equal(t, all[10:150], keys(t, it, err))
+	it, err = db.ReverseIterator([]byte("0010"), []byte("0150"))
+	assert.Equal(t, reversed[len(all)-150:len(all)-10], keys(t, it, err))
+}
+
+func TestMemDBIteratorSnapshot(t *testing.T) {
+	db := NewMemDB()
+	assert.Nil(t, db.Set([]byte("a"), []byte("1")))
+	assert.Nil(t, db.Set([]byte("b"), []byte("1")))
+
+	it, err := db.Iterator(nil, nil)
+	assert.Nil(t, err)
+	defer it.Close()
+
+	// an open iterator blocks neither reads nor writes, and doesn't see them
+	assert.Nil(t, db.Set([]byte("a"), []byte("2")))
+	assert.Nil(t, db.Delete([]byte("b")))
+	batch := db.NewBatch()
+	assert.Nil(t, batch.Set([]byte("c"), []byte("2")))
+	assert.Nil(t, batch.Write())
+	assert.Nil(t, batch.Close())
+	nested, err := db.Iterator(nil, nil)
+	assert.Equal(t, []string{"a", "c"}, keys(t, nested, err))
+
+	var kvs []string
+	for ; it.Valid(); it.Next() {
+		kvs = append(kvs, string(it.Key())+"="+string(it.Value()))
+	}
+	assert.Equal(t, []string{"a=1", "b=1"}, kvs)
+
+	value, err := db.Get([]byte("a"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("2"), value)
+}
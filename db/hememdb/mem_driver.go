@@ -0,0 +1,11 @@
+package hememdb
+
+import (
+	"github.com/terra-money/mantlemint/db/heleveldb"
+)
+
+// NewMemDBDriver keeps height-versioned keys in memory, in the same layout as heleveldb does
+// over goleveldb; Name and Dir of config are ignored. Everything is gone once it is dropped.
+func NewMemDBDriver(config *heleveldb.DriverConfig) *heleveldb.Driver {
+	return heleveldb.NewDriver(NewMemDB(), config)
+}
@@ -0,0 +1,19 @@
+package hememdb
+
+import (
+	"testing"
+
+	"github.com/terra-money/mantlemint/db/heleveldb"
+	"github.com/terra-money/mantlemint/db/hld"
+	"github.com/terra-money/mantlemint/db/hld/hldtest"
+)
+
+func TestMemDBDriver(t *testing.T) {
+	for name, mode := range map[string]int{"asc": heleveldb.DriverModeKeySuffixAsc, "desc": heleveldb.DriverModeKeySuffixDesc} {
+		t.Run(name, func(t *testing.T) {
+			hldtest.RunDriverTests(t, func(t *testing.T) hld.HeightLimitEnabledDB {
+				return NewMemDBDriver(&heleveldb.DriverConfig{Mode: mode})
+			})
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package hememdb
+
+import (
+	"bytes"
+
+	dbm "github.com/cometbft/cometbft-db"
+	"github.com/google/btree"
+)
+
+// memIteratorChunkSize is how many items an iterator buffers from its snapshot at a time
+const memIteratorChunkSize = 64
+
+var _ dbm.Iterator = (*memIterator)(nil)
+
+// memIterator walks [start, end) of a private snapshot of the tree, a chunk at a time
+type memIterator struct {
+	tree      *btree.BTreeG[item]
+	start     []byte
+	end       []byte
+	isReverse bool
+
+	items     []item
+	pos       int
+	exhausted bool
+}
+
+func newMemIterator(tree *btree.BTreeG[item], start, end []byte, isReverse bool) *memIterator {
+	itr := &memIterator{
+		tree:      tree,
+		start:     start,
+		end:       end,
+		isReverse: isReverse,
+	}
+	itr.fetch()
+	return itr
+}
+
+// fetch buffers the next chunk, continuing after the last buffered item
+func (itr *memIterator) fetch() {
+	var cursor []byte
+	if len(itr.items) > 0 {
+		cursor = itr.items[len(itr.items)-1].key
+	}
+	itr.items = itr.items[:0]
+	itr.pos = 0
+	if itr.exhausted {
+		return
+	}
+
+	visit := func(i item) bool {
+		if cursor != nil && bytes.Equal(i.key, cursor) {
+			return true
+		}
+		if itr.isReverse {
+			if itr.start != nil && bytes.Compare(i.key, itr.start) < 0 {
+				return false
+			}
+			// end is exclusive
+			if itr.end != nil && bytes.Compare(i.key, itr.end) >= 0 {
+				return true
+			}
+		} else if itr.end != nil && bytes.Compare(i.key, itr.end) >= 0 {
+			return false
+		}
+		itr.items = append(itr.items, i)
+		return len(itr.items) < memIteratorChunkSize
+	}
+
+	switch {
+	case !itr.isReverse && cursor != nil:
+		itr.tree.AscendGreaterOrEqual(item{key: cursor}, visit)
+	case !itr.isReverse && itr.start != nil:
+		itr.tree.AscendGreaterOrEqual(item{key: itr.start}, visit)
+	case !itr.isReverse:
+		itr.tree.Ascend(visit)
+	case cursor != nil:
+		itr.tree.DescendLessOrEqual(item{key: cursor}, visit)
+	case itr.end != nil:
+		itr.tree.DescendLessOrEqual(item{key: itr.end}, visit)
+	default:
+		itr.tree.Descend(visit)
+	}
+	itr.exhausted = len(itr.items) < memIteratorChunkSize
+}
+
+func (itr *memIterator) Domain() ([]byte, []byte) {
+	return itr.start, itr.end
+}
+
+func (itr *memIterator) Valid() bool {
+	return itr.pos < len(itr.items)
+}
+
+func (itr *memIterator) Key() []byte {
+	itr.assertIsValid()
+	return cp(itr.items[itr.pos].key)
+}
+
+func (itr *memIterator) Value() []byte {
+	itr.assertIsValid()
+	return cp(itr.items[itr.pos].value)
+}
+
+func (itr *memIterator) Next() {
+	itr.assertIsValid()
+	itr.pos++
+	if itr.pos == len(itr.items) {
+		itr.fetch()
+	}
+}
+
+func (itr *memIterator) Error() error {
+	return nil
+}
+
+func (itr *memIterator) Close() error {
+	itr.tree = nil
+	itr.items = nil
+	return nil
+}
+
+func (itr *memIterator) assertIsValid() {
+	if !itr.Valid() {
+		panic("iterator is invalid")
+	}
+}
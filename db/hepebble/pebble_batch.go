@@ -0,0 +1,69 @@
+package hepebble
+
+import (
+	"github.com/cockroachdb/pebble"
+	dbm "github.com/cometbft/cometbft-db"
+)
+
+var _ dbm.Batch = (*pebbleBatch)(nil)
+
+type pebbleBatch struct {
+	batch *pebble.Batch
+}
+
+func newPebbleBatch(p *PebbleDB) *pebbleBatch {
+	return &pebbleBatch{
+		batch: p.db.NewBatch(),
+	}
+}
+
+func (b *pebbleBatch) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if b.batch == nil {
+		return errBatchClosed
+	}
+	return b.batch.Set(key, value, nil)
+}
+
+func (b *pebbleBatch) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if b.batch == nil {
+		return errBatchClosed
+	}
+	return b.batch.Delete(key, nil)
+}
+
+func (b *pebbleBatch) Write() error {
+	return b.write(pebble.NoSync)
+}
+
+func (b *pebbleBatch) WriteSync() error {
+	return b.write(pebble.Sync)
+}
+
+func (b *pebbleBatch) write(opts *pebble.WriteOptions) error {
+	if b.batch == nil {
+		return errBatchClosed
+	}
+	if err := b.batch.Commit(opts); err != nil {
+		return err
+	}
+	// as with goleveldb, a written batch can't be reused; callers still Close it
+	return b.Close()
+}
+
+func (b *pebbleBatch) Close() error {
+	if b.batch == nil {
+		return nil
+	}
+	err := b.batch.Close()
+	b.batch = nil
+	return err
+}
@@ -0,0 +1,180 @@
+package hepebble
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/bloom"
+	dbm "github.com/cometbft/cometbft-db"
+)
+
+var (
+	errKeyEmpty    = errors.New("key cannot be empty")
+	errValueNil    = errors.New("value cannot be nil")
+	errBatchClosed = errors.New("batch has been written or closed")
+)
+
+var _ dbm.DB = (*PebbleDB)(nil)
+
+// PebbleDB implements dbm.DB over pebble, with the same semantics as dbm.GoLevelDB
+type PebbleDB struct {
+	db *pebble.DB
+}
+
+const (
+	defaultCacheSize    = 512 << 20 // 512 MB
+	defaultMemTableSize = 64 << 20  // 64 MB
+)
+
+// NewPebbleDB opens, or creates, name.db under dir with DefaultOptions
+func NewPebbleDB(name string, dir string) (*PebbleDB, error) {
+	opts := DefaultOptions()
+	// the db holds its own reference to the cache
+	defer opts.Cache.Unref()
+	return NewPebbleDBWithOpts(name, dir, opts)
+}
+
+func NewPebbleDBWithOpts(name string, dir string, opts *pebble.Options) (*PebbleDB, error) {
+	db, err := pebble.Open(filepath.Join(dir, name+".db"), opts)
+	if err != nil {
+		return nil, err
+	}
+	return &PebbleDB{db: db}, nil
+}
+
+// DefaultOptions tunes pebble for mantlemint: point reads of state at a height dominate,
+// so every level has a bloom filter and blocks are cached; writes come in large batches,
+// one per block, so memtables are larger than pebble's 4 MB default.
+// The returned Cache is referenced once and must be Unref'd by the caller after opening.
+func DefaultOptions() *pebble.Options {
+	opts := &pebble.Options{
+		Cache:                       pebble.NewCache(defaultCacheSize),
+		MemTableSize:                defaultMemTableSize,
+		MemTableStopWritesThreshold: 4,
+		L0CompactionThreshold:       2,
+		L0StopWritesThreshold:       1000,
+		LBaseMaxBytes:               defaultMemTableSize * 4,
+		MaxOpenFiles:                16384,
+		MaxConcurrentCompactions:    func() int { return 3 },
+		Levels:                      make([]pebble.LevelOptions, 7),
+	}
+
+	for i := range opts.Levels {
+		level := &opts.Levels[i]
+		level.BlockSize = 32 << 10       // 32 KB
+		level.IndexBlockSize = 256 << 10 // 256 KB
+		level.FilterPolicy = bloom.FilterPolicy(10)
+		level.FilterType = pebble.TableFilter
+		if i == 0 {
+			level.TargetFileSize = 4 << 20 // 4 MB
+		} else {
+			level.TargetFileSize = opts.Levels[i-1].TargetFileSize * 2
+		}
+	}
+
+	return opts.EnsureDefaults()
+}
+
+func (p *PebbleDB) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	value, closer, err := p.db.Get(key)
+	if errors.Is(err, pebble.ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+
+	// value is only valid until closer is closed
+	return cp(value), nil
+}
+
+func (p *PebbleDB) Has(key []byte) (bool, error) {
+	value, err := p.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return value != nil, nil
+}
+
+func (p *PebbleDB) Set(key []byte, value []byte) error {
+	return p.set(key, value, pebble.NoSync)
+}
+
+func (p *PebbleDB) SetSync(key []byte, value []byte) error {
+	return p.set(key, value, pebble.Sync)
+}
+
+func (p *PebbleDB) set(key []byte, value []byte, opts *pebble.WriteOptions) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	return p.db.Set(key, value, opts)
+}
+
+func (p *PebbleDB) Delete(key []byte) error {
+	return p.delete(key, pebble.NoSync)
+}
+
+func (p *PebbleDB) DeleteSync(key []byte) error {
+	return p.delete(key, pebble.Sync)
+}
+
+func (p *PebbleDB) delete(key []byte, opts *pebble.WriteOptions) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	return p.db.Delete(key, opts)
+}
+
+func (p *PebbleDB) Iterator(start, end []byte) (dbm.Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	return newPebbleIterator(p.db.NewIter(&pebble.IterOptions{LowerBound: start, UpperBound: end}), start, end, false), nil
+}
+
+func (p *PebbleDB) ReverseIterator(start, end []byte) (dbm.Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	return newPebbleIterator(p.db.NewIter(&pebble.IterOptions{LowerBound: start, UpperBound: end}), start, end, true), nil
+}
+
+func (p *PebbleDB) NewBatch() dbm.Batch {
+	return newPebbleBatch(p)
+}
+
+func (p *PebbleDB) Close() error {
+	return p.db.Close()
+}
+
+func (p *PebbleDB) Print() error {
+	fmt.Println(p.db.Metrics().String())
+
+	it := p.db.NewIter(nil)
+	defer it.Close()
+	for it.First(); it.Valid(); it.Next() {
+		fmt.Printf("[%X]:\t[%X]\n", it.Key(), it.Value())
+	}
+	return it.Error()
+}
+
+func (p *PebbleDB) Stats() map[string]string {
+	return map[string]string{
+		"pebble.metrics": p.db.Metrics().String(),
+	}
+}
+
+func cp(bz []byte) []byte {
+	ret := make([]byte, len(bz))
+	copy(ret, bz)
+	return ret
+}
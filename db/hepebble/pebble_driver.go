@@ -0,0 +1,15 @@
+package hepebble
+
+import (
+	"github.com/terra-money/mantlemint/db/heleveldb"
+)
+
+// NewPebbleDriver opens config.Name under config.Dir with pebble,
+// keeping height-versioned keys in the same layout as heleveldb does over goleveldb
+func NewPebbleDriver(config *heleveldb.DriverConfig) (*heleveldb.Driver, error) {
+	db, err := NewPebbleDB(config.Name, config.Dir)
+	if err != nil {
+		return nil, err
+	}
+	return heleveldb.NewDriver(db, config), nil
+}
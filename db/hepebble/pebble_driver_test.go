@@ -0,0 +1,23 @@
+package hepebble
+
+import (
+	"testing"
+
+	"github.com/terra-money/mantlemint/db/heleveldb"
+	"github.com/terra-money/mantlemint/db/hld"
+	"github.com/terra-money/mantlemint/db/hld/hldtest"
+)
+
+func TestPebbleDriver(t *testing.T) {
+	for name, mode := range map[string]int{"asc": heleveldb.DriverModeKeySuffixAsc, "desc": heleveldb.DriverModeKeySuffixDesc} {
+		t.Run(name, func(t *testing.T) {
+			hldtest.RunDriverTests(t, func(t *testing.T) hld.HeightLimitEnabledDB {
+				driver, err := NewPebbleDriver(&heleveldb.DriverConfig{Name: "test", Dir: t.TempDir(), Mode: mode})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return driver
+			})
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package hepebble
+
+import (
+	"github.com/cockroachdb/pebble"
+	dbm "github.com/cometbft/cometbft-db"
+)
+
+var _ dbm.Iterator = (*pebbleIterator)(nil)
+
+// pebbleIterator walks [start, end) of a pebble iterator bounded to the same range
+type pebbleIterator struct {
+	source    *pebble.Iterator
+	start     []byte
+	end       []byte
+	isReverse bool
+}
+
+func newPebbleIterator(source *pebble.Iterator, start, end []byte, isReverse bool) *pebbleIterator {
+	if isReverse {
+		source.Last()
+	} else {
+		source.First()
+	}
+	return &pebbleIterator{
+		source:    source,
+		start:     start,
+		end:       end,
+		isReverse: isReverse,
+	}
+}
+
+func (itr *pebbleIterator) Domain() ([]byte, []byte) {
+	return itr.start, itr.end
+}
+
+func (itr *pebbleIterator) Valid() bool {
+	return itr.source.Valid()
+}
+
+func (itr *pebbleIterator) Key() []byte {
+	itr.assertIsValid()
+	return cp(itr.source.Key())
+}
+
+func (itr *pebbleIterator) Value() []byte {
+	itr.assertIsValid()
+	return cp(itr.source.Value())
+}
+
+func (itr *pebbleIterator) Next() {
+	itr.assertIsValid()
+	if itr.isReverse {
+		itr.source.Prev()
+	} else {
+		itr.source.Next()
+	}
+}
+
+func (itr *pebbleIterator) Error() error {
+	return itr.source.Error()
+}
+
+func (itr *pebbleIterator) Close() error {
+	return itr.source.Close()
+}
+
+func (itr *pebbleIterator) assertIsValid() {
+	if !itr.Valid() {
+		panic("iterator is invalid")
+	}
+}
@@ -6,9 +6,10 @@ const (
 	maxHeight = 9223372036854775807
 )
 
-// Cluster returns /1000 of the height; useful for clustering records in different partitions
-func (h Height) Cluster() Height {
-	return h / 1000
+// Cluster returns the index of the partition of size heights that h falls in;
+// useful for clustering records in different partitions
+func (h Height) Cluster(size int64) Height {
+	return h / Height(size)
 }
 
 func (h Height) ToInt64() int64 {
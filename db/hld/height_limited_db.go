@@ -81,6 +81,14 @@ func (hld *HeightLimitedDB) GetCurrentReadHeight() int64 {
 	return hld.readHeight
 }
 
+// CheckReadHeight returns an error if the underlying db can't serve readHeight anymore
+func (hld *HeightLimitedDB) CheckReadHeight() error {
+	if checker, ok := hld.odb.(HeightChecker); ok {
+		return checker.CheckHeight(hld.readHeight)
+	}
+	return nil
+}
+
 // SetWriteHeight sets a target write height in the db driver.
 // - Writer uses writeHeight to append along with the key, so later when fetching with the driver
 // you can find the latest known key/value pair before the writeHeight
@@ -0,0 +1,164 @@
+// Package hldtest is a conformance suite for hld.HeightLimitEnabledDB drivers.
+package hldtest
+
+import (
+	"testing"
+
+	dbm "github.com/cometbft/cometbft-db"
+	"github.com/stretchr/testify/assert"
+	"github.com/terra-money/mantlemint/db/hld"
+)
+
+// RunDriverTests checks the driver returned by open, an empty one per test, against the behaviour
+// mantlemint relies on; every driver should pass it in each of its modes
+func RunDriverTests(t *testing.T, open func(t *testing.T) hld.HeightLimitEnabledDB) {
+	t.Run("GetAtHeight", func(t *testing.T) { testGetAtHeight(t, open(t)) })
+	t.Run("EmptyValue", func(t *testing.T) { testEmptyValue(t, open(t)) })
+	t.Run("HeightOrdering", func(t *testing.T) { testHeightOrdering(t, open(t)) })
+	t.Run("Iterator", func(t *testing.T) { testIterator(t, open(t)) })
+	t.Run("ReverseIterator", func(t *testing.T) { testReverseIterator(t, open(t)) })
+}
+
+// write applies kvs at height; an empty value deletes the key
+func write(t *testing.T, db hld.HeightLimitEnabledDB, height int64, kvs map[string]string) {
+	batch := db.NewBatch(height)
+	defer batch.Close()
+	for k, v := range kvs {
+		if v == "" {
+			assert.Nil(t, batch.Delete([]byte(k)))
+		} else {
+			assert.Nil(t, batch.Set([]byte(k), []byte(v)))
+		}
+	}
+	assert.Nil(t, batch.WriteSync())
+}
+
+func get(t *testing.T, db hld.HeightLimitEnabledDB, height int64, key string) []byte {
+	value, err := db.Get(height, []byte(key))
+	assert.Nil(t, err)
+	return value
+}
+
+func has(t *testing.T, db hld.HeightLimitEnabledDB, height int64, key string) bool {
+	ok, err := db.Has(height, []byte(key))
+	assert.Nil(t, err)
+	return ok
+}
+
+// seed writes a history of three heights; at height 2, b is deleted
+func seed(t *testing.T, db hld.HeightLimitEnabledDB) {
+	write(t, db, 1, map[string]string{"a": "1", "b": "1", "d": "1"})
+	write(t, db, 2, map[string]string{"a": "2", "b": ""})
+	write(t, db, 3, map[string]string{"c": "3"})
+}
+
+// collect reads every key value pair of it, closing it
+func collect(t *testing.T, it dbm.Iterator, err error) []string {
+	assert.Nil(t, err)
+	defer it.Close()
+
+	var kvs []string
+	for ; it.Valid(); it.Next() {
+		kvs = append(kvs, string(it.Key())+"="+string(it.Value()))
+	}
+	assert.Nil(t, it.Error())
+	return kvs
+}
+
+func testGetAtHeight(t *testing.T, db hld.HeightLimitEnabledDB) {
+	defer db.Close()
+	seed(t, db)
+
+	assert.Equal(t, []byte("1"), get(t, db, 1, "a"))
+	assert.Equal(t, []byte("2"), get(t, db, 2, "a"))
+	assert.Equal(t, []byte("2"), get(t, db, 3, "a"))
+
+	// deleted from height 2 on
+	assert.Equal(t, []byte("1"), get(t, db, 1, "b"))
+	assert.True(t, has(t, db, 1, "b"))
+	assert.Nil(t, get(t, db, 2, "b"))
+	assert.False(t, has(t, db, 2, "b"))
+
+	// not yet written
+	assert.Nil(t, get(t, db, 2, "c"))
+	assert.False(t, has(t, db, 2, "c"))
+	assert.Equal(t, []byte("3"), get(t, db, 3, "c"))
+	assert.Nil(t, get(t, db, 3, "never"))
+
+	// height 0 reads the current state
+	assert.Equal(t, []byte("2"), get(t, db, 0, "a"))
+	assert.Nil(t, get(t, db, 0, "b"))
+	assert.False(t, has(t, db, 0, "b"))
+	assert.True(t, has(t, db, 0, "c"))
+}
+
+func testEmptyValue(t *testing.T, db hld.HeightLimitEnabledDB) {
+	defer db.Close()
+	batch := db.NewBatch(1)
+	assert.Nil(t, batch.Set([]byte("empty"), []byte{}))
+	assert.Nil(t, batch.WriteSync())
+	assert.Nil(t, batch.Close())
+
+	// an empty value is set, unlike a missing one
+	for _, height := range []int64{0, 1} {
+		value := get(t, db, height, "empty")
+		assert.NotNil(t, value)
+		assert.Empty(t, value)
+		assert.True(t, has(t, db, height, "empty"))
+	}
+}
+
+func testHeightOrdering(t *testing.T, db hld.HeightLimitEnabledDB) {
+	defer db.Close()
+
+	// heights compare as numbers, not as their lowest bytes
+	write(t, db, 255, map[string]string{"a": "255"})
+	write(t, db, 256, map[string]string{"a": "256"})
+	write(t, db, 70000, map[string]string{"a": "70000"})
+
+	assert.Nil(t, get(t, db, 254, "a"))
+	assert.Equal(t, []byte("255"), get(t, db, 255, "a"))
+	assert.Equal(t, []byte("256"), get(t, db, 256, "a"))
+	assert.Equal(t, []byte("256"), get(t, db, 69999, "a"))
+	assert.Equal(t, []byte("70000"), get(t, db, 70000, "a"))
+	assert.Equal(t, []byte("70000"), get(t, db, 0, "a"))
+}
+
+func testIterator(t *testing.T, db hld.HeightLimitEnabledDB) {
+	defer db.Close()
+	seed(t, db)
+
+	it, err := db.Iterator(1, nil, nil)
+	assert.Equal(t, []string{"a=1", "b=1", "d=1"}, collect(t, it, err))
+	it, err = db.Iterator(2, nil, nil)
+	assert.Equal(t, []string{"a=2", "d=1"}, collect(t, it, err))
+	it, err = db.Iterator(3, nil, nil)
+	assert.Equal(t, []string{"a=2", "c=3", "d=1"}, collect(t, it, err))
+	it, err = db.Iterator(0, nil, nil)
+	assert.Equal(t, []string{"a=2", "c=3", "d=1"}, collect(t, it, err))
+
+	// start is inclusive, end exclusive
+	it, err = db.Iterator(1, []byte("b"), []byte("d"))
+	assert.Equal(t, []string{"b=1"}, collect(t, it, err))
+	it, err = db.Iterator(3, []byte("b"), []byte("d"))
+	assert.Equal(t, []string{"c=3"}, collect(t, it, err))
+	it, err = db.Iterator(0, []byte("b"), nil)
+	assert.Equal(t, []string{"c=3", "d=1"}, collect(t, it, err))
+}
+
+func testReverseIterator(t *testing.T, db hld.HeightLimitEnabledDB) {
+	defer db.Close()
+	seed(t, db)
+
+	it, err := db.ReverseIterator(1, nil, nil)
+	assert.Equal(t, []string{"d=1", "b=1", "a=1"}, collect(t, it, err))
+	it, err = db.ReverseIterator(3, nil, nil)
+	assert.Equal(t, []string{"d=1", "c=3", "a=2"}, collect(t, it, err))
+	it, err = db.ReverseIterator(0, nil, nil)
+	assert.Equal(t, []string{"d=1", "c=3", "a=2"}, collect(t, it, err))
+
+	it, err = db.ReverseIterator(1, []byte("b"), []byte("d"))
+	assert.Equal(t, []string{"b=1"}, collect(t, it, err))
+	it, err = db.ReverseIterator(0, nil, []byte("d"))
+	assert.Equal(t, []string{"c=3", "a=2"}, collect(t, it, err))
+}
@@ -62,6 +62,12 @@ type HeightLimitEnabledDB interface {
 	Stats() map[string]string
 }
 
+// HeightChecker is implemented by drivers that no longer hold every height, e.g. once pruned
+type HeightChecker interface {
+	// CheckHeight returns an error if height can't be read anymore
+	CheckHeight(height int64) error
+}
+
 type HeightLimitEnabledIterator interface {
 	dbm.Iterator
 }
@@ -0,0 +1,215 @@
+package rollbackable
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+
+	dbm "github.com/cometbft/cometbft-db"
+	"github.com/terra-money/mantlemint/lib"
+)
+
+// Journal persists undo records of the last N heights in the db,
+// so that a range of blocks can be reverted even after a restart.
+//
+// Each entry lives under prefix + BigEndian(height), and holds every key
+// touched at that height along with the value it had before.
+type Journal struct {
+	db         dbm.DB
+	prefix     []byte
+	keepBlocks int64
+}
+
+func NewJournal(db dbm.DB, prefix []byte, keepBlocks int64) *Journal {
+	return &Journal{
+		db:         db,
+		prefix:     prefix,
+		keepBlocks: keepBlocks,
+	}
+}
+
+func (j *Journal) key(height int64) []byte {
+	return lib.ConcatBytes(j.prefix, lib.UintToBigEndian(uint64(height)))
+}
+
+// Append writes undo records for height into batch, and drops every entry
+// that has fallen out of the retention window.
+// Journal entries must go into the same batch as the data they revert.
+// Nothing is journaled for a batch without records; there is nothing to revert.
+func (j *Journal) Append(batch dbm.Batch, height int64, records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if err := batch.Set(j.key(height), encodeRecords(records)); err != nil {
+		return err
+	}
+
+	expired := height - j.keepBlocks
+	if expired < 1 {
+		return nil
+	}
+
+	// a height jump, e.g. import-iavl or a snapshot restore, leaves more than one entry behind
+	it, err := j.db.Iterator(j.key(0), j.key(expired+1))
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+	for ; it.Valid(); it.Next() {
+		if err := batch.Delete(append([]byte{}, it.Key()...)); err != nil {
+			return err
+		}
+	}
+	return it.Error()
+}
+
+// Range returns the lowest and the highest height present in the journal.
+// Both are 0 if the journal is empty.
+func (j *Journal) Range() (lowest int64, highest int64, err error) {
+	pdb := dbm.NewPrefixDB(j.db, j.prefix)
+
+	it, err := pdb.Iterator(nil, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	if it.Valid() {
+		lowest = int64(lib.BigEndianToUint(it.Key()))
+	}
+	if err := it.Close(); err != nil {
+		return 0, 0, err
+	}
+
+	rit, err := pdb.ReverseIterator(nil, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rit.Close()
+	if rit.Valid() {
+		highest = int64(lib.BigEndianToUint(rit.Key()))
+	}
+
+	return lowest, highest, nil
+}
+
+// Load returns undo records for height, or nil if there is no entry.
+func (j *Journal) Load(height int64) ([]Record, error) {
+	data, err := j.db.Get(j.key(height))
+	if err != nil || data == nil {
+		return nil, err
+	}
+	return decodeRecords(data)
+}
+
+// Rewind reverts every height above toHeight, newest first.
+// Each height is reverted (and its entry removed) in a single synced batch,
+// so an interrupted rewind can simply be run again.
+// Nothing is reverted unless every height above toHeight has an entry.
+func (j *Journal) Rewind(toHeight int64) error {
+	lowest, highest, err := j.Range()
+	if err != nil {
+		return err
+	}
+	if highest <= toHeight {
+		return fmt.Errorf("nothing to rollback; journal ends at height %d", highest)
+	}
+	if lowest == 0 || lowest > toHeight+1 {
+		return fmt.Errorf("rollback journal only covers heights %d..%d, cannot rollback to %d", lowest, highest, toHeight)
+	}
+	for height := toHeight + 1; height <= highest; height++ {
+		if has, err := j.db.Has(j.key(height)); err != nil {
+			return err
+		} else if !has {
+			return fmt.Errorf("rollback journal entry for height %d is missing, cannot rollback to %d", height, toHeight)
+		}
+	}
+
+	for height := highest; height > toHeight; height-- {
+		records, err := j.Load(height)
+		if err != nil {
+			return err
+		}
+		if records == nil {
+			return fmt.Errorf("rollback journal entry for height %d is missing", height)
+		}
+
+		batch := j.db.NewBatch()
+		for i := len(records) - 1; i >= 0; i-- {
+			if records[i].Deleted {
+				err = batch.Delete(records[i].Key)
+			} else {
+				err = batch.Set(records[i].Key, records[i].Value)
+			}
+			if err != nil {
+				batch.Close()
+				return err
+			}
+		}
+		if err := batch.Delete(j.key(height)); err != nil {
+			batch.Close()
+			return err
+		}
+		if err := batch.WriteSync(); err != nil {
+			batch.Close()
+			return err
+		}
+		if err := batch.Close(); err != nil {
+			return err
+		}
+
+		log.Printf("[rollback-journal] reverted height %d, %d records", height, len(records))
+	}
+
+	return nil
+}
+
+// encodeRecords serializes records as
+// [uvarint keyLen][key][deleted][uvarint valueLen][value]...
+func encodeRecords(records []Record) []byte {
+	buf := make([]byte, 0, 64*len(records))
+	for _, record := range records {
+		buf = binary.AppendUvarint(buf, uint64(len(record.Key)))
+		buf = append(buf, record.Key...)
+		if record.Deleted {
+			buf = append(buf, 1)
+			continue
+		}
+		buf = append(buf, 0)
+		buf = binary.AppendUvarint(buf, uint64(len(record.Value)))
+		buf = append(buf, record.Value...)
+	}
+	return buf
+}
+
+func decodeRecords(data []byte) ([]Record, error) {
+	errCorrupted := fmt.Errorf("corrupted rollback journal entry")
+	records := make([]Record, 0)
+
+	readBytes := func() ([]byte, bool) {
+		length, n := binary.Uvarint(data)
+		if n <= 0 || uint64(len(data)-n) < length {
+			return nil, false
+		}
+		out := data[n : n+int(length)]
+		data = data[n+int(length):]
+		return out, true
+	}
+
+	for len(data) > 0 {
+		key, ok := readBytes()
+		if !ok || len(data) == 0 {
+			return nil, errCorrupted
+		}
+		deleted := data[0] == 1
+		data = data[1:]
+
+		record := Record{Key: key, Deleted: deleted}
+		if !deleted {
+			if record.Value, ok = readBytes(); !ok {
+				return nil, errCorrupted
+			}
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
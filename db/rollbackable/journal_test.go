@@ -0,0 +1,97 @@
+package rollbackable
+
+import (
+	"testing"
+
+	dbm "github.com/cometbft/cometbft-db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournalRewind(t *testing.T) {
+	db := dbm.NewMemDB()
+	journal := NewJournal(db, []byte{0xff}, 2)
+
+	write := func(height int64, kvs map[string]string) {
+		batch := NewRollbackableBatch(db)
+		for k, v := range kvs {
+			if v == "" {
+				assert.Nil(t, batch.Delete([]byte(k)))
+			} else {
+				assert.Nil(t, batch.Set([]byte(k), []byte(v)))
+			}
+		}
+		assert.Nil(t, journal.Append(batch.Batch, height, batch.Records))
+		assert.Nil(t, batch.WriteSync())
+		assert.Nil(t, batch.Close())
+	}
+
+	write(1, map[string]string{"a": "1", "b": "1"})
+	write(2, map[string]string{"a": "2", "c": "2"})
+	write(3, map[string]string{"b": "", "c": "3"})
+
+	// height 1 has fallen out of the window
+	lowest, highest, err := journal.Range()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), lowest)
+	assert.Equal(t, int64(3), highest)
+	assert.NotNil(t, journal.Rewind(0))
+
+	assert.Nil(t, journal.Rewind(1))
+
+	get := func(key string) []byte {
+		v, err := db.Get([]byte(key))
+		assert.Nil(t, err)
+		return v
+	}
+	assert.Equal(t, []byte("1"), get("a"))
+	assert.Equal(t, []byte("1"), get("b"))
+	assert.Nil(t, get("c"))
+
+	_, highest, err = journal.Range()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), highest)
+}
+
+func TestJournalRewindGap(t *testing.T) {
+	db := dbm.NewMemDB()
+	journal := NewJournal(db, []byte{0xff}, 10)
+
+	write := func(height int64, key string) {
+		batch := NewRollbackableBatch(db)
+		if key != "" {
+			assert.Nil(t, batch.Set([]byte(key), []byte{1}))
+		}
+		assert.Nil(t, journal.Append(batch.Batch, height, batch.Records))
+		assert.Nil(t, batch.WriteSync())
+		assert.Nil(t, batch.Close())
+	}
+
+	// an empty batch leaves no entry
+	write(1, "")
+	lowest, _, err := journal.Range()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), lowest)
+
+	// a stale entry far below the window must not make up for the missing ones
+	write(1, "a")
+	write(5, "b")
+	write(6, "c")
+	assert.ErrorContains(t, journal.Rewind(2), "height 3 is missing")
+
+	// nothing was reverted
+	v, err := db.Get([]byte("c"))
+	assert.Nil(t, err)
+	assert.NotNil(t, v)
+
+	assert.Nil(t, journal.Rewind(4))
+	v, err = db.Get([]byte("b"))
+	assert.Nil(t, err)
+	assert.Nil(t, v)
+
+	// every entry left below the window by a height jump is dropped
+	write(20, "d")
+	lowest, highest, err := journal.Range()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(20), lowest)
+	assert.Equal(t, int64(20), highest)
+}
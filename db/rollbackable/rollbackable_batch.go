@@ -10,12 +10,22 @@ type HasRollbackBatch interface {
 
 var _ dbm.Batch = (*RollbackableBatch)(nil)
 
+// Record holds the value of Key before it was touched by the batch
+type Record struct {
+	Key     []byte
+	Value   []byte
+	Deleted bool
+}
+
 type RollbackableBatch struct {
 	dbm.Batch
 
 	db            dbm.DB
 	RollbackBatch dbm.Batch
 	RecordCount   int
+
+	// Records keeps undo records in write order, for Journal
+	Records []Record
 }
 
 func NewRollbackableBatch(db dbm.DB) *RollbackableBatch {
@@ -34,8 +44,10 @@ func (b *RollbackableBatch) backup(key []byte) error {
 		return err
 	}
 	if data == nil {
+		b.Records = append(b.Records, Record{Key: key, Deleted: true})
 		return b.RollbackBatch.Delete(key)
 	} else {
+		b.Records = append(b.Records, Record{Key: key, Value: data})
 		return b.RollbackBatch.Set(key, data)
 	}
 }
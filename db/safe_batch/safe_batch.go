@@ -16,6 +16,7 @@ type SafeBatchDBCloser interface {
 	dbm.DB
 	Open()
 	Flush() (dbm.Batch, error)
+	Discard() error
 }
 
 type SafeBatchDB struct {
@@ -44,6 +45,17 @@ func (s *SafeBatchDB) Flush() (dbm.Batch, error) {
 	}
 }
 
+// discard batch without writing
+func (s *SafeBatchDB) Discard() error {
+	if s.batch == nil {
+		return nil
+	}
+
+	err := s.batch.Close()
+	s.batch = nil
+	return err
+}
+
 func NewSafeBatchDB(db dbm.DB) dbm.DB {
 	return &SafeBatchDB{
 		db:    db,
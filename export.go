@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	tmjson "github.com/cometbft/cometbft/libs/json"
+	tmlog "github.com/cometbft/cometbft/libs/log"
+	tendermint "github.com/cometbft/cometbft/types"
+	"github.com/cosmos/cosmos-sdk/baseapp"
+	servertypes "github.com/cosmos/cosmos-sdk/server/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/terra-money/mantlemint/config"
+	"github.com/terra-money/mantlemint/store/rootmulti"
+)
+
+// appStateExporter is implemented by apps that can dump their state as genesis
+type appStateExporter interface {
+	ExportAppStateAndValidators(forZeroHeight bool, jailAllowedAddrs, modulesToExport []string) (servertypes.ExportedApp, error)
+}
+
+// newExportCmd handles `mantlemint export --output <file>`.
+// It exports app state at the current state height as a genesis file, without syncing or running genesis.
+func newExportCmd() *cobra.Command {
+	var output string
+	var forZeroHeight bool
+	var modules []string
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export app state at the current state height as genesis",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			mantlemintConfig, configErr := config.NewConfig(cmd.Flags())
+			if configErr != nil {
+				return configErr
+			}
+			return exportState(mantlemintConfig, output, forZeroHeight, modules)
+		},
+	}
+	cmd.Flags().StringVar(&output, "output", "", "file to write genesis to; defaults to stdout")
+	cmd.Flags().BoolVar(&forZeroHeight, "for-zero-height", false, "export state to start a new chain from height 0")
+	cmd.Flags().StringSliceVar(&modules, "modules", nil, "modules to export; defaults to all")
+
+	return cmd
+}
+
+func exportState(mantlemintConfig config.Config, output string, forZeroHeight bool, modules []string) error {
+	view := openStoreView(mantlemintConfig)
+	defer view.Close()
+
+	lastState, stateErr := view.stateStore.Load()
+	if stateErr != nil {
+		return stateErr
+	}
+	if lastState.LastBlockHeight == 0 {
+		return fmt.Errorf("[export] nothing to export; no block was injected yet")
+	}
+
+	factory, factoryErr := configuredAppFactory(mantlemintConfig, lastState.LastBlockHeight)
+	if factoryErr != nil {
+		return factoryErr
+	}
+
+	// the app loads the latest version of mantlemint db; nothing is written as no block is run
+	logger := tmlog.NewTMLogger(os.Stderr)
+	cms := rootmulti.NewStore(view.db, logger, view.db)
+	app := factory.NewApp(
+		logger,
+		view.db,
+		mantlemintConfig.Home,
+		newAppOptions(mantlemintConfig, viper.GetViper()),
+		func(ba *baseapp.BaseApp) {
+			ba.SetCMS(cms)
+		},
+		baseapp.SetChainID(mantlemintConfig.ChainID),
+	)
+
+	exporter, ok := app.(appStateExporter)
+	if !ok {
+		return fmt.Errorf("[export] app %T can't export its state", app)
+	}
+	exported, exportErr := exporter.ExportAppStateAndValidators(forZeroHeight, nil, modules)
+	if exportErr != nil {
+		return exportErr
+	}
+
+	genesisDoc := getGenesisDoc(mantlemintConfig.GenesisPath)
+	genesisDoc.AppState = exported.AppState
+	genesisDoc.Validators = exported.Validators
+	genesisDoc.InitialHeight = exported.Height
+	if exported.ConsensusParams != nil {
+		consensusParams := tendermint.ConsensusParamsFromProto(*exported.ConsensusParams)
+		genesisDoc.ConsensusParams = &consensusParams
+	}
+
+	if output == "" {
+		out, marshalErr := tmjson.MarshalIndent(genesisDoc, "", "  ")
+		if marshalErr != nil {
+			return marshalErr
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+	if err := genesisDoc.SaveAs(output); err != nil {
+		return err
+	}
+
+	log.Printf("[export] exported state at height %d to %s", exported.Height, output)
+	return nil
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+
+	dbm "github.com/cometbft/cometbft-db"
+	cmtstate "github.com/cometbft/cometbft/proto/tendermint/state"
+	"github.com/cometbft/cometbft/state"
+	"github.com/cometbft/cometbft/store"
+	"github.com/cometbft/cometbft/version"
+	storetypes "github.com/cosmos/cosmos-sdk/store/types"
+	upgradetypes "github.com/cosmos/cosmos-sdk/x/upgrade/types"
+	"github.com/cosmos/iavl"
+	"github.com/spf13/cobra"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/terra-money/mantlemint/config"
+	"github.com/terra-money/mantlemint/db/hld"
+	"github.com/terra-money/mantlemint/db/wrapped"
+	"github.com/terra-money/mantlemint/store/rootmulti"
+)
+
+// importBatchSize is how many keys go into a single db batch on import
+const importBatchSize = 10000
+
+// newImportIAVLCmd handles `mantlemint import-iavl --height <height>`.
+// It bootstraps an empty mantlemint home from the data directory of a stopped full node:
+// every module store of application.db at version height, tendermint state at height from
+// state.db and blockstore.db, and wasm contract code. mantlemint then syncs from height+1.
+func newImportIAVLCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import-iavl",
+		Short: "Bootstrap mantlemint db from application.db of a stopped full node at a height",
+		Args:  cobra.NoArgs,
+	}
+	height := cmd.Flags().Int64("height", 0, "height to import; application.db must still hold this version")
+	nodeData := cmd.Flags().String("node-data", "", "data directory of the full node, i.e. $TERRA_HOME/data; defaults to BLOCKSTORE_DIR")
+	_ = cmd.MarkFlagRequired("height")
+	cmd.RunE = func(cmd *cobra.Command, _ []string) error {
+		mantlemintConfig, err := config.NewConfig(cmd.Flags())
+		if err != nil {
+			return err
+		}
+		dir := *nodeData
+		if dir == "" {
+			dir = mantlemintConfig.BlockStoreDir
+		}
+		if dir == "" {
+			return fmt.Errorf("[import-iavl] --node-data is required")
+		}
+		return importIAVL(mantlemintConfig, *height, dir)
+	}
+
+	return cmd
+}
+
+func importIAVL(mantlemintConfig config.Config, height int64, nodeData string) error {
+	if height < 1 {
+		return fmt.Errorf("[import-iavl] height must be positive, got %d", height)
+	}
+	if mantlemintConfig.DBBackend == config.DBBackendMemDB {
+		return fmt.Errorf("[import-iavl] nothing to import into with storage.backend %s", config.DBBackendMemDB)
+	}
+
+	// never mix imported state into existing state
+	for _, name := range []string{mantlemintConfig.MantlemintDB, mantlemintConfig.IndexerDB} {
+		if _, err := os.Stat(filepath.Join(mantlemintConfig.Home, name+".db")); err == nil {
+			return fmt.Errorf("[import-iavl] %s.db already exists in %s", name, mantlemintConfig.Home)
+		}
+	}
+	dataDir := filepath.Join(mantlemintConfig.Home, "data")
+	wasmDir := filepath.Join(dataDir, "wasm")
+	if entries, err := os.ReadDir(wasmDir); err == nil && len(entries) > 0 {
+		return fmt.Errorf("[import-iavl] %s is not empty", wasmDir)
+	}
+
+	nodeDBs := make(map[string]dbm.DB)
+	defer func() {
+		for _, db := range nodeDBs {
+			db.Close()
+		}
+	}()
+	for _, name := range []string{"application", "state", "blockstore"} {
+		db, err := dbm.NewGoLevelDBWithOpts(name, nodeData, &opt.Options{ReadOnly: true})
+		if err != nil {
+			return fmt.Errorf("[import-iavl] failed to open %s.db in %s; is the node stopped? %w", name, nodeData, err)
+		}
+		nodeDBs[name] = db
+	}
+
+	commitInfo, commitInfoErr := rootmulti.GetCommitInfo(nodeDBs["application"], height)
+	if commitInfoErr != nil {
+		return fmt.Errorf("[import-iavl] application.db has no version %d: %w", height, commitInfoErr)
+	}
+	importedState, stateErr := loadImportedState(nodeDBs["state"], nodeDBs["blockstore"], height, commitInfo.Hash())
+	if stateErr != nil {
+		return fmt.Errorf("[import-iavl] %w", stateErr)
+	}
+	if importedState.ChainID != mantlemintConfig.ChainID {
+		return fmt.Errorf("[import-iavl] node is of chain %s, expected %s", importedState.ChainID, mantlemintConfig.ChainID)
+	}
+
+	// import next to the final location, moving it in place only once complete
+	scratchConfig := mantlemintConfig
+	scratchConfig.MantlemintDB = mantlemintConfig.MantlemintDB + ".import"
+	// the journal keeps a single entry per height, which a multi-batch import would overwrite;
+	// there is nothing to roll back to below height anyway
+	scratchConfig.RollbackJournalSize = 0
+	scratchPath := filepath.Join(mantlemintConfig.Home, scratchConfig.MantlemintDB+".db")
+	wasmScratch := wasmDir + ".import"
+	defer func() {
+		_ = os.RemoveAll(scratchPath)
+		_ = os.RemoveAll(wasmScratch)
+	}()
+
+	// left over by an interrupted import
+	if err := os.RemoveAll(scratchPath); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(wasmScratch); err != nil {
+		return err
+	}
+
+	log.Printf("[import-iavl] importing height %d of chain %s from %s", height, importedState.ChainID, nodeData)
+
+	ldb := openDB(scratchConfig)
+	hldb := hld.ApplyHeightLimitedDB(ldb, &hld.HeightLimitedDBConfig{})
+	hldb.SetWriteHeight(height)
+
+	importErr := importStores(nodeDBs["application"], hldb, height, commitInfo.StoreInfos)
+	if importErr == nil {
+		// tendermint state goes last; it marks mantlemint db as initialized
+		stateStore := state.NewStore(wrapped.NewWrappedDB(hldb), state.StoreOptions{
+			DiscardABCIResponses: false,
+		})
+		importErr = stateStore.Bootstrap(importedState)
+	}
+	closeErr := ldb.Close()
+	if importErr != nil {
+		return fmt.Errorf("[import-iavl] %w", importErr)
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+
+	// wasm VM keeps contract code under data/wasm, outside of application.db
+	if err := copyDir(filepath.Join(nodeData, "wasm"), wasmScratch); err != nil {
+		return fmt.Errorf("[import-iavl] wasm: %w", err)
+	}
+	// store migrations of the next app depend on the plan the node halted for, if any
+	upgradeInfo, upgradeInfoErr := os.ReadFile(filepath.Join(nodeData, upgradetypes.UpgradeInfoFilename))
+	if upgradeInfoErr != nil && !errors.Is(upgradeInfoErr, os.ErrNotExist) {
+		return upgradeInfoErr
+	}
+
+	if err := os.Rename(scratchPath, filepath.Join(mantlemintConfig.Home, mantlemintConfig.MantlemintDB+".db")); err != nil {
+		return err
+	}
+	if err := os.Remove(wasmDir); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.MkdirAll(wasmScratch, 0o755); err != nil {
+		return err
+	}
+	if err := os.Rename(wasmScratch, wasmDir); err != nil {
+		return err
+	}
+	if upgradeInfo != nil {
+		if err := os.WriteFile(filepath.Join(dataDir, upgradetypes.UpgradeInfoFilename), upgradeInfo, 0o600); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("[import-iavl] done; mantlemint will resume from height %d", height+1)
+	return nil
+}
+
+// importStores writes every key of each iavl store of appDB at version into db
+func importStores(appDB dbm.DB, db dbm.DB, version int64, storeInfos []storetypes.StoreInfo) error {
+	storeNames := make([]string, 0, len(storeInfos))
+	for _, storeInfo := range storeInfos {
+		storeNames = append(storeNames, storeInfo.Name)
+
+		prefix := rootmulti.StorePrefix(storeInfo.Name)
+		tree, err := iavl.NewMutableTree(dbm.NewPrefixDB(appDB, prefix), 0, true)
+		if err != nil {
+			return fmt.Errorf("%s: %w", storeInfo.Name, err)
+		}
+		immutable, err := tree.GetImmutable(version)
+		if err != nil {
+			return fmt.Errorf("%s: failed to load version %d: %w", storeInfo.Name, version, err)
+		}
+		if immutable.Size() == 0 {
+			log.Printf("[import-iavl] %s: empty", storeInfo.Name)
+			continue
+		}
+
+		imported, err := importTree(immutable, db, prefix)
+		if err != nil {
+			return fmt.Errorf("%s: %w", storeInfo.Name, err)
+		}
+		log.Printf("[import-iavl] %s: %d keys", storeInfo.Name, imported)
+	}
+
+	return rootmulti.FlushImportedVersion(db, version, storeNames)
+}
+
+func importTree(tree *iavl.ImmutableTree, db dbm.DB, prefix []byte) (int64, error) {
+	it, err := tree.Iterator(nil, nil, true)
+	if err != nil {
+		return 0, err
+	}
+	defer it.Close()
+
+	batch := db.NewBatch()
+	defer func() { batch.Close() }()
+
+	var imported int64
+	pending := 0
+	for ; it.Valid(); it.Next() {
+		key := append(append(make([]byte, 0, len(prefix)+len(it.Key())), prefix...), it.Key()...)
+		if err := batch.Set(key, it.Value()); err != nil {
+			return imported, err
+		}
+		imported++
+		if pending++; pending < importBatchSize {
+			continue
+		}
+
+		if err := batch.Write(); err != nil {
+			return imported, err
+		}
+		batch.Close()
+		batch = db.NewBatch()
+		pending = 0
+	}
+	if err := it.Error(); err != nil {
+		return imported, err
+	}
+	return imported, batch.WriteSync()
+}
+
+// loadImportedState rebuilds tendermint state as of committing height from a node's state.db and
+// blockstore.db, the way state sync does from light blocks
+func loadImportedState(stateDB, blockStoreDB dbm.DB, height int64, appHash []byte) (state.State, error) {
+	stateStore := state.NewStore(stateDB, state.StoreOptions{
+		DiscardABCIResponses: false,
+	})
+	blockStore := store.NewBlockStore(blockStoreDB)
+
+	latest, err := stateStore.Load()
+	if err != nil {
+		return state.State{}, err
+	}
+	if latest.LastBlockHeight < height {
+		return state.State{}, fmt.Errorf("node is at height %d, below %d", latest.LastBlockHeight, height)
+	}
+
+	meta := blockStore.LoadBlockMeta(height)
+	if meta == nil {
+		return state.State{}, fmt.Errorf("blockstore has no block %d; blockstore spans %d..%d", height, blockStore.Base(), blockStore.Height())
+	}
+
+	lastValidators, err := stateStore.LoadValidators(height)
+	if err != nil {
+		return state.State{}, err
+	}
+	validators, err := stateStore.LoadValidators(height + 1)
+	if err != nil {
+		return state.State{}, err
+	}
+	nextValidators, err := stateStore.LoadValidators(height + 2)
+	if err != nil {
+		return state.State{}, err
+	}
+	consensusParams, err := stateStore.LoadConsensusParams(height + 1)
+	if err != nil {
+		return state.State{}, err
+	}
+
+	// results of height are committed to by the header of height+1
+	var lastResultsHash []byte
+	if next := blockStore.LoadBlockMeta(height + 1); next != nil {
+		lastResultsHash = next.Header.LastResultsHash
+	} else {
+		responses, err := stateStore.LoadABCIResponses(height)
+		if err != nil {
+			return state.State{}, fmt.Errorf("no block %d nor abci responses of %d to take results hash from: %w", height+1, height, err)
+		}
+		lastResultsHash = state.ABCIResponsesResultsHash(responses)
+	}
+
+	return state.State{
+		Version: cmtstate.Version{
+			Consensus: meta.Header.Version,
+			Software:  version.TMCoreSemVer,
+		},
+		ChainID:       latest.ChainID,
+		InitialHeight: latest.InitialHeight,
+
+		LastBlockHeight: height,
+		LastBlockID:     meta.BlockID,
+		LastBlockTime:   meta.Header.Time,
+
+		NextValidators:              nextValidators,
+		Validators:                  validators,
+		LastValidators:              lastValidators,
+		LastHeightValidatorsChanged: height + 2,
+
+		ConsensusParams:                  consensusParams,
+		LastHeightConsensusParamsChanged: height + 1,
+
+		LastResultsHash: lastResultsHash,
+		AppHash:         appHash,
+	}, nil
+}
+
+// copyDir copies every file under src to dst; a missing src copies nothing
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && path == src {
+			return filepath.SkipDir
+		} else if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		return os.WriteFile(target, content, 0o644)
+	})
+}
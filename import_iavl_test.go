@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+
+	dbm "github.com/cometbft/cometbft-db"
+	storetypes "github.com/cosmos/cosmos-sdk/store/types"
+	"github.com/cosmos/iavl"
+	"github.com/stretchr/testify/assert"
+	"github.com/terra-money/mantlemint/config"
+	"github.com/terra-money/mantlemint/db/hld"
+	"github.com/terra-money/mantlemint/store/rootmulti"
+)
+
+func TestImportStores(t *testing.T) {
+	appDB := dbm.NewMemDB()
+	for _, name := range []string{"bank", "empty"} {
+		tree, err := iavl.NewMutableTree(dbm.NewPrefixDB(appDB, rootmulti.StorePrefix(name)), 0, true)
+		assert.Nil(t, err)
+		if name == "bank" {
+			_, _ = tree.Set([]byte("a"), []byte("1"))
+			_, _ = tree.Set([]byte("b"), []byte("1"))
+		}
+		_, _, err = tree.SaveVersion()
+		assert.Nil(t, err)
+
+		// later versions are not imported
+		_, _ = tree.Set([]byte("b"), []byte("2"))
+		_, _ = tree.Set([]byte("c"), []byte("2"))
+		_, _, err = tree.SaveVersion()
+		assert.Nil(t, err)
+	}
+
+	ldb := openDB(config.Config{Home: t.TempDir(), MantlemintDB: "mantlemint"})
+	defer ldb.Close()
+	hldb := hld.ApplyHeightLimitedDB(ldb, &hld.HeightLimitedDBConfig{})
+	hldb.SetWriteHeight(1)
+
+	assert.Nil(t, importStores(appDB, hldb, 1, []storetypes.StoreInfo{{Name: "empty"}, {Name: "bank"}}))
+
+	bank := dbm.NewPrefixDB(hldb, rootmulti.StorePrefix("bank"))
+	value, err := bank.Get([]byte("b"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("1"), value)
+	value, err = bank.Get([]byte("c"))
+	assert.Nil(t, err)
+	assert.Nil(t, value)
+
+	assert.Equal(t, int64(1), rootmulti.GetLatestVersion(hldb))
+	commitInfo, err := rootmulti.GetCommitInfo(hldb, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "bank", commitInfo.StoreInfos[0].Name)
+	assert.Equal(t, "empty", commitInfo.StoreInfos[1].Name)
+}
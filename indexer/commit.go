@@ -0,0 +1,154 @@
+package indexer
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	dbm "github.com/cometbft/cometbft-db"
+	tm "github.com/cometbft/cometbft/types"
+	"github.com/terra-money/mantlemint/lib"
+	"github.com/terra-money/mantlemint/mantlemint"
+)
+
+// Every Run writes, in the same batch as the indexed data,
+// - the height marker, which tells the last height the indexer has committed
+// - the list of keys written at that height, so that the height can be truncated
+// These allow the indexer to be reconciled against the state db on startup.
+var (
+	lastHeightKey      = []byte("indexer/lastHeight")
+	keysByHeightPrefix = []byte("indexer/keys:")
+	getKeysByHeightKey = func(height int64) []byte {
+		return lib.ConcatBytes(keysByHeightPrefix, lib.UintToBigEndian(uint64(height)))
+	}
+)
+
+// BlockSource provides an already injected block along with its events, for reindexing
+type BlockSource func(height int64) (*tm.Block, *tm.BlockID, *mantlemint.EventCollector, error)
+
+var _ dbm.Batch = (*recordingBatch)(nil)
+
+// recordingBatch keeps track of every key written by indexers
+type recordingBatch struct {
+	dbm.Batch
+	keys [][]byte
+}
+
+func (b *recordingBatch) Set(key, value []byte) error {
+	b.keys = append(b.keys, append([]byte{}, key...))
+	return b.Batch.Set(key, value)
+}
+
+func (b *recordingBatch) Delete(key []byte) error {
+	b.keys = append(b.keys, append([]byte{}, key...))
+	return b.Batch.Delete(key)
+}
+
+func (b *recordingBatch) commitHeight(height int64) error {
+	keysJSON, err := json.Marshal(b.keys)
+	if err != nil {
+		return err
+	}
+	if err := b.Batch.Set(getKeysByHeightKey(height), keysJSON); err != nil {
+		return err
+	}
+	return b.Batch.Set(lastHeightKey, lib.UintToBigEndian(uint64(height)))
+}
+
+// LastHeight returns the last height committed to the indexer db.
+// ok is false if the indexer db was created before height markers were introduced.
+func (idx *Indexer) LastHeight() (height int64, ok bool, err error) {
+	data, err := idx.db.Get(lastHeightKey)
+	if err != nil || data == nil {
+		return 0, false, err
+	}
+	return int64(lib.BigEndianToUint(data)), true, nil
+}
+
+// ResetHeight moves the height marker to height, without touching indexed data.
+// Heights above it are indexed again (and overwritten) by the next Reconcile.
+func (idx *Indexer) ResetHeight(height int64) error {
+	return idx.db.SetSync(lastHeightKey, lib.UintToBigEndian(uint64(height)))
+}
+
+// Truncate removes everything indexed above toHeight
+func (idx *Indexer) Truncate(toHeight int64) error {
+	lastHeight, _, err := idx.LastHeight()
+	if err != nil {
+		return err
+	}
+
+	for height := lastHeight; height > toHeight; height-- {
+		keysJSON, err := idx.db.Get(getKeysByHeightKey(height))
+		if err != nil {
+			return err
+		}
+
+		var keys [][]byte
+		if keysJSON == nil {
+			log.Printf("[indexer] no key records for height %d, only moving the height marker", height)
+		} else if err := json.Unmarshal(keysJSON, &keys); err != nil {
+			return err
+		}
+
+		batch := idx.db.NewBatch()
+		for _, key := range keys {
+			if err := batch.Delete(key); err != nil {
+				batch.Close()
+				return err
+			}
+		}
+		if err := batch.Delete(getKeysByHeightKey(height)); err != nil {
+			batch.Close()
+			return err
+		}
+		if err := batch.Set(lastHeightKey, lib.UintToBigEndian(uint64(height-1))); err != nil {
+			batch.Close()
+			return err
+		}
+		if err := batch.WriteSync(); err != nil {
+			batch.Close()
+			return err
+		}
+		if err := batch.Close(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Reconcile brings the indexer to stateHeight;
+// heights indexed ahead of the state db are truncated, and missing heights are reindexed from source.
+func (idx *Indexer) Reconcile(stateHeight int64, source BlockSource) error {
+	lastHeight, ok, err := idx.LastHeight()
+	if err != nil {
+		return err
+	}
+
+	// indexer db without a height marker is assumed to be in sync
+	if !ok {
+		log.Printf("[indexer] no height marker found, assuming indexer is at height %d", stateHeight)
+		return idx.db.SetSync(lastHeightKey, lib.UintToBigEndian(uint64(stateHeight)))
+	}
+
+	switch {
+	case lastHeight > stateHeight:
+		log.Printf("[indexer] indexer(%d) is ahead of state(%d), truncating...", lastHeight, stateHeight)
+		return idx.Truncate(stateHeight)
+
+	case lastHeight < stateHeight:
+		log.Printf("[indexer] indexer(%d) is behind state(%d), reindexing...", lastHeight, stateHeight)
+		for height := lastHeight + 1; height <= stateHeight; height++ {
+			block, blockID, evc, sourceErr := source(height)
+			if sourceErr != nil {
+				return fmt.Errorf("failed to reindex height %d: %w", height, sourceErr)
+			}
+			if err := idx.Run(block, blockID, evc); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package indexer
+
+import (
+	"fmt"
+	"testing"
+
+	dbm "github.com/cometbft/cometbft-db"
+	tm "github.com/cometbft/cometbft/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/terra-money/mantlemint/db/hememdb"
+	"github.com/terra-money/mantlemint/mantlemint"
+)
+
+func TestIndexerReconcile(t *testing.T) {
+	idx := NewIndexerWithDB(hememdb.NewMemDB())
+	defer idx.Close()
+
+	idx.RegisterIndexerService("test", func(batch dbm.Batch, block *tm.Block, _ *tm.BlockID, _ *mantlemint.EventCollector) error {
+		return batch.Set([]byte(fmt.Sprintf("test/%d", block.Height)), []byte("indexed"))
+	})
+	source := func(height int64) (*tm.Block, *tm.BlockID, *mantlemint.EventCollector, error) {
+		return &tm.Block{Header: tm.Header{Height: height}}, nil, nil, nil
+	}
+
+	// fresh indexer db is assumed to be in sync
+	assert.Nil(t, idx.Reconcile(2, source))
+	height, ok, err := idx.LastHeight()
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), height)
+
+	// behind: reindex 3..5
+	assert.Nil(t, idx.Reconcile(5, source))
+	height, _, _ = idx.LastHeight()
+	assert.Equal(t, int64(5), height)
+	v, _ := idx.db.Get([]byte("test/4"))
+	assert.Equal(t, []byte("indexed"), v)
+
+	// ahead: truncate 4..5
+	assert.Nil(t, idx.Reconcile(3, source))
+	height, _, _ = idx.LastHeight()
+	assert.Equal(t, int64(3), height)
+	v, _ = idx.db.Get([]byte("test/4"))
+	assert.Nil(t, v)
+	v, _ = idx.db.Get([]byte("test/3"))
+	assert.Equal(t, []byte("indexed"), v)
+}
@@ -22,14 +22,18 @@ func NewIndexer(dbName, path string) (*Indexer, error) {
 	if indexerDBError != nil {
 		return nil, indexerDBError
 	}
+	return NewIndexerWithDB(indexerDB), nil
+}
 
-	indexerDBCompressed := snappy.NewSnappyDB(indexerDB, snappy.CompatModeEnabled)
+// NewIndexerWithDB keeps indexed data in db, e.g. an in-memory one
+func NewIndexerWithDB(db dbm.DB) *Indexer {
+	indexerDBCompressed := snappy.NewSnappyDB(db, snappy.CompatModeEnabled)
 
 	return &Indexer{
 		db:          indexerDBCompressed,
 		indexerTags: []string{},
 		indexers:    []IndexFunc{},
-	}, nil
+	}
 }
 
 func (idx *Indexer) RegisterIndexerService(tag string, indexerFunc IndexFunc) {
@@ -38,7 +42,9 @@ func (idx *Indexer) RegisterIndexerService(tag string, indexerFunc IndexFunc) {
 }
 
 func (idx *Indexer) Run(block *tm.Block, blockId *tm.BlockID, evc *mantlemint.EventCollector) error {
-	batch := idx.db.NewBatch()
+	batch := &recordingBatch{Batch: idx.db.NewBatch()}
+	defer batch.Close()
+
 	tStart := time.Now()
 	for _, indexerFunc := range idx.indexers {
 		if indexerErr := indexerFunc(batch, block, blockId, evc); indexerErr != nil {
@@ -48,6 +54,11 @@ func (idx *Indexer) Run(block *tm.Block, blockId *tm.BlockID, evc *mantlemint.Ev
 	tEnd := time.Now()
 	fmt.Printf("[indexer] finished %d indexers, %dms\n", len(idx.indexers), tEnd.Sub(tStart).Milliseconds())
 
+	// height marker goes into the same batch
+	if err := batch.commitHeight(block.Height); err != nil {
+		return err
+	}
+
 	if err := batch.WriteSync(); err != nil {
 		return err
 	}
@@ -59,6 +70,11 @@ func (idx *Indexer) Run(block *tm.Block, blockId *tm.BlockID, evc *mantlemint.Ev
 	return nil
 }
 
+// Close closes the underlying indexer db
+func (idx *Indexer) Close() error {
+	return idx.db.Close()
+}
+
 func (idx *Indexer) RegisterRESTRoute(router *mux.Router, registerer RESTRouteRegisterer) {
 	registerer(router, idx.db)
 }
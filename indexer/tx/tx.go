@@ -4,20 +4,25 @@ import (
 	"encoding/json"
 	"fmt"
 
-	terra "github.com/classic-terra/core/v3/app"
 	dbm "github.com/cometbft/cometbft-db"
 	tmjson "github.com/cometbft/cometbft/libs/json"
 	tm "github.com/cometbft/cometbft/types"
+	"github.com/cosmos/cosmos-sdk/client"
 	"github.com/terra-money/mantlemint/indexer"
 	"github.com/terra-money/mantlemint/mantlemint"
 )
 
-var cdc = terra.MakeEncodingConfig()
+// NewIndexTx creates the tx indexer for a chain with the given tx codec
+func NewIndexTx(txConfig client.TxConfig) indexer.IndexFunc {
+	return indexer.CreateIndexer(func(batch dbm.Batch, block *tm.Block, blockID *tm.BlockID, evc *mantlemint.EventCollector) error {
+		return indexTx(txConfig, batch, block, evc)
+	})
+}
 
-var IndexTx = indexer.CreateIndexer(func(batch dbm.Batch, block *tm.Block, blockID *tm.BlockID, evc *mantlemint.EventCollector) error {
+func indexTx(txConfig client.TxConfig, batch dbm.Batch, block *tm.Block, evc *mantlemint.EventCollector) error {
 	// encoder; proto -> mem -> json
-	txDecoder := cdc.TxConfig.TxDecoder()
-	jsonEncoder := cdc.TxConfig.TxJSONEncoder()
+	txDecoder := txConfig.TxDecoder()
+	jsonEncoder := txConfig.TxJSONEncoder()
 
 	txHashes := make([]string, len(block.Txs))
 	txRecords := make([]TxRecord, len(block.Txs))
@@ -98,4 +103,4 @@ var IndexTx = indexer.CreateIndexer(func(batch dbm.Batch, block *tm.Block, block
 	}
 
 	return nil
-})
+}
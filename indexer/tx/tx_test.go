@@ -6,6 +6,7 @@ import (
 	"os"
 	"testing"
 
+	terraapp "github.com/classic-terra/core/v3/app"
 	dbm "github.com/cometbft/cometbft-db"
 	tmjson "github.com/cometbft/cometbft/libs/json"
 	tendermint "github.com/cometbft/cometbft/types"
@@ -33,7 +34,7 @@ func TestIndexTx(t *testing.T) {
 	_ = evc.PublishEventTx(event)
 
 	batch := db.NewBatch()
-	if err := IndexTx(batch, block, nil, evc); err != nil {
+	if err := NewIndexTx(terraapp.MakeEncodingConfig().TxConfig)(batch, block, nil, evc); err != nil {
 		panic(err)
 	}
 	_ = batch.WriteSync()
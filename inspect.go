@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/terra-money/mantlemint/config"
+	"github.com/terra-money/mantlemint/indexer"
+)
+
+// inspection is what `mantlemint inspect` prints
+type inspection struct {
+	ChainID         string    `json:"chain_id"`
+	LastBlockHeight int64     `json:"last_block_height"`
+	BlockStore      [2]int64  `json:"block_store"`
+	LastBlockTime   time.Time `json:"last_block_time"`
+	AppHash         string    `json:"app_hash"`
+	LastResultsHash string    `json:"last_results_hash"`
+	RollbackJournal [2]int64  `json:"rollback_journal"`
+	IndexerHeight   *int64    `json:"indexer_height"`
+}
+
+// newInspectCmd handles `mantlemint inspect`.
+// It prints state of mantlemint db and indexer db, without syncing or starting the app.
+func newInspectCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "inspect",
+		Short: "Print state of mantlemint db and indexer db",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			mantlemintConfig, err := config.NewConfig(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			return inspect(mantlemintConfig)
+		},
+	}
+}
+
+func inspect(mantlemintConfig config.Config) error {
+	view := openStoreView(mantlemintConfig)
+	defer view.Close()
+
+	lastState, stateErr := view.stateStore.Load()
+	if stateErr != nil {
+		return stateErr
+	}
+	result := inspection{
+		ChainID:         lastState.ChainID,
+		LastBlockHeight: lastState.LastBlockHeight,
+		BlockStore:      [2]int64{view.blockStore.Base(), view.blockStore.Height()},
+		LastBlockTime:   lastState.LastBlockTime,
+		AppHash:         fmt.Sprintf("%X", lastState.AppHash),
+		LastResultsHash: fmt.Sprintf("%X", lastState.LastResultsHash),
+	}
+
+	lowest, highest, rangeErr := view.ldb.RollbackRange()
+	if rangeErr != nil {
+		return rangeErr
+	}
+	result.RollbackJournal = [2]int64{lowest, highest}
+
+	indexerDB, indexerDBErr := openSessionDB(mantlemintConfig, mantlemintConfig.IndexerDB)
+	if indexerDBErr != nil {
+		return indexerDBErr
+	}
+	indexerInstance := indexer.NewIndexerWithDB(indexerDB)
+	defer indexerInstance.Close()
+
+	if indexerHeight, ok, err := indexerInstance.LastHeight(); err != nil {
+		return err
+	} else if ok {
+		result.IndexerHeight = &indexerHeight
+	}
+
+	out, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/terra-money/mantlemint/db/heleveldb"
+)
+
+// heightJob runs fn in the background every interval,
+// with the last height committed by the sync loop
+type heightJob struct {
+	name     string
+	interval time.Duration
+	fn       func(ctx context.Context, height int64) error
+	height   atomic.Int64
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// startHeightJob starts running fn from height on; a nil fn makes a job that does nothing
+func startHeightJob(name string, interval time.Duration, height int64, fn func(ctx context.Context, height int64) error) *heightJob {
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &heightJob{
+		name:     name,
+		interval: interval,
+		fn:       fn,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	j.height.Store(height)
+
+	if fn == nil {
+		close(j.done)
+		return j
+	}
+	go j.run(ctx)
+	return j
+}
+
+func (j *heightJob) run(ctx context.Context) {
+	defer close(j.done)
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.fn(ctx, j.height.Load()); err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("[%s] %v", j.name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Commit records height as the last committed height
+func (j *heightJob) Commit(height int64) {
+	j.height.Store(height)
+}
+
+// Close stops the job, waiting for a running fn to return
+func (j *heightJob) Close() error {
+	j.cancel()
+	<-j.done
+	return nil
+}
+
+// startPruner prunes ldb by policy in the background; it does nothing if policy is disabled
+func startPruner(ldb *heleveldb.Driver, policy heleveldb.PruningPolicy, interval time.Duration, height int64) *heightJob {
+	if floor := ldb.PrunedFloor(); floor > 0 {
+		log.Printf("[pruning] heights below %d are pruned", floor)
+	}
+	if !policy.Enabled() {
+		return startHeightJob("pruning", interval, height, nil)
+	}
+
+	return startHeightJob("pruning", interval, height, func(ctx context.Context, height int64) error {
+		started := time.Now()
+		stats, err := ldb.Prune(ctx, policy, height)
+		if err != nil {
+			return err
+		}
+		if stats.Keys > 0 {
+			log.Printf("[pruning] deleted %d versions of %d keys, keeping heights from %d, in %s", stats.Deleted, stats.Keys, stats.Floor, time.Since(started))
+		}
+		return nil
+	})
+}
+
+// startTiering moves history of ldb to cold partitions in the background; it does nothing
+// if cold storage is disabled
+func startTiering(ldb *heleveldb.Driver, enabled bool, interval time.Duration, height int64) *heightJob {
+	if !enabled {
+		return startHeightJob("tiering", interval, height, nil)
+	}
+
+	return startHeightJob("tiering", interval, height, func(ctx context.Context, height int64) error {
+		started := time.Now()
+		stats, err := ldb.MoveToCold(ctx, height)
+		if err != nil {
+			return err
+		}
+		if stats.Keys > 0 {
+			log.Printf("[tiering] copied %d and deleted %d versions of %d keys, history below %d is cold, in %s", stats.Copied, stats.Deleted, stats.Keys, stats.To, time.Since(started))
+		}
+		return nil
+	})
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+
+	"github.com/cosmos/cosmos-sdk/x/crisis"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/terra-money/mantlemint/config"
+)
+
+func main() {
+	if err := newRootCmd().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// newRootCmd builds the mantlemint CLI.
+// Running without a subcommand is the same as `mantlemint start`, for existing deployments.
+func newRootCmd() *cobra.Command {
+	startCmd := newStartCmd()
+
+	rootCmd := &cobra.Command{
+		Use:   "mantlemint",
+		Short: "Fast query node for Terra Classic",
+		Long: "mantlemint syncs blocks from RPC/websocket endpoints and serves LCD/gRPC queries.\n" +
+			"Configuration is read from mantlemint.toml, overridden by environment variables and flags; see README.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE:         startCmd.RunE,
+	}
+	addStartFlags(rootCmd)
+	config.AddFlags(rootCmd.PersistentFlags())
+
+	rootCmd.AddCommand(
+		startCmd,
+		newRollbackCmd(),
+		newExportCmd(),
+		newBlocksCmd(),
+		newInspectCmd(),
+		newReindexCmd(),
+		newVerifyCmd(),
+		newVersionCmd(),
+		newConfigCmd(),
+		newSnapshotCmd(),
+		newImportIAVLCmd(),
+	)
+
+	return rootCmd
+}
+
+func newStartCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "start",
+		Short: "Sync blocks and serve queries",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			// app options (e.g. crisis invariant check) are read through viper
+			if err := viper.BindPFlag(crisis.FlagSkipGenesisInvariants, cmd.Flags().Lookup(crisis.FlagSkipGenesisInvariants)); err != nil {
+				return err
+			}
+			mantlemintConfig, err := config.NewConfig(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			start(mantlemintConfig)
+			return nil
+		},
+	}
+	addStartFlags(cmd)
+
+	return cmd
+}
+
+func addStartFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool(crisis.FlagSkipGenesisInvariants, false, "Skip x/crisis invariants check on startup")
+}
+
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect mantlemint configuration",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "print",
+		Short: "Print the effective configuration, merged from mantlemint.toml, env vars and flags",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			mantlemintConfig, err := config.Load(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			mantlemintConfig.Print()
+
+			// printed regardless, so that the offending values can be seen
+			return mantlemintConfig.Validate()
+		},
+	})
+
+	return cmd
+}
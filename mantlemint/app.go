@@ -0,0 +1,45 @@
+package mantlemint
+
+import (
+	dbm "github.com/cometbft/cometbft-db"
+	tmlog "github.com/cometbft/cometbft/libs/log"
+	"github.com/cosmos/cosmos-sdk/baseapp"
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/codec"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/server/api"
+	"github.com/cosmos/cosmos-sdk/server/config"
+	servertypes "github.com/cosmos/cosmos-sdk/server/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// EncodingConfig holds codecs of a chain
+type EncodingConfig struct {
+	InterfaceRegistry codectypes.InterfaceRegistry
+	Codec             codec.Codec
+	TxConfig          client.TxConfig
+	Amino             *codec.LegacyAmino
+}
+
+// AppFactory provides everything chain specific to mantlemint;
+// sync, storage, indexers and RPC work with any Cosmos SDK chain implementing it.
+type AppFactory interface {
+	// ConfigureSDK sets bech32 prefixes, coin type and the like. config is sealed afterwards
+	ConfigureSDK(config *sdk.Config)
+
+	// EncodingConfig returns codecs of the chain
+	EncodingConfig() EncodingConfig
+
+	// NewApp creates the app on db. baseAppOptions must be applied to the BaseApp,
+	// as mantlemint relies on them to replace the commit multistore.
+	NewApp(
+		logger tmlog.Logger,
+		db dbm.DB,
+		home string,
+		appOpts servertypes.AppOptions,
+		baseAppOptions ...func(*baseapp.BaseApp),
+	) servertypes.Application
+
+	// RegisterAPIRoutes registers LCD routes and gRPC services of app
+	RegisterAPIRoutes(app servertypes.Application, apiSrv *api.Server, apiConfig config.APIConfig, clientCtx client.Context)
+}
@@ -0,0 +1,130 @@
+package mantlemint
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cometbft/cometbft/state"
+	tendermint "github.com/cometbft/cometbft/types"
+	"github.com/gorilla/mux"
+)
+
+const EndpointGETSyncStatus = "/sync/status"
+
+// HaltCondition stops syncing once a block at Height or later, or with time at Time or later,
+// is committed; zero values disable either, as halt-height and halt-time of app.toml do
+type HaltCondition struct {
+	Height int64
+	Time   time.Time
+}
+
+func (c HaltCondition) Enabled() bool {
+	return c.Height > 0 || !c.Time.IsZero()
+}
+
+// ReachedBy tells whether committing a block with height and blockTime fulfils c
+func (c HaltCondition) ReachedBy(height int64, blockTime time.Time) bool {
+	if c.Height > 0 && height >= c.Height {
+		return true
+	}
+	return !c.Time.IsZero() && !blockTime.Before(c.Time)
+}
+
+func (c HaltCondition) String() string {
+	var conditions []string
+	if c.Height > 0 {
+		conditions = append(conditions, fmt.Sprintf("halt height %d", c.Height))
+	}
+	if !c.Time.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("halt time %s", c.Time.UTC().Format(time.RFC3339)))
+	}
+	return strings.Join(conditions, " or ")
+}
+
+// SyncStatus is served under EndpointGETSyncStatus
+type SyncStatus struct {
+	Height     int64      `json:"height"`
+	BlockTime  time.Time  `json:"block_time"`
+	HaltHeight int64      `json:"halt_height,omitempty"`
+	HaltTime   *time.Time `json:"halt_time,omitempty"`
+	Halted     bool       `json:"halted"`
+	HaltedAt   *time.Time `json:"halted_at,omitempty"`
+}
+
+// Halter freezes mantlemint at a HaltCondition, keeping track of the last committed block
+type Halter struct {
+	condition HaltCondition
+	mtx       *sync.RWMutex
+	status    SyncStatus
+}
+
+func NewHalter(condition HaltCondition) *Halter {
+	h := &Halter{
+		condition: condition,
+		mtx:       new(sync.RWMutex),
+	}
+	h.status.HaltHeight = condition.Height
+	if !condition.Time.IsZero() {
+		haltTime := condition.Time.UTC()
+		h.status.HaltTime = &haltTime
+	}
+	return h
+}
+
+// Commit records block as committed, and returns true once the halt condition is reached;
+// no more blocks are to be committed afterwards
+func (h *Halter) Commit(block *tendermint.Block) bool {
+	return h.commit(block.Height, block.Time)
+}
+
+// Resume records the last committed block of lastState on startup;
+// true if it already fulfils the halt condition
+func (h *Halter) Resume(lastState state.State) bool {
+	if lastState.LastBlockHeight == 0 {
+		return false
+	}
+	return h.commit(lastState.LastBlockHeight, lastState.LastBlockTime)
+}
+
+func (h *Halter) commit(height int64, blockTime time.Time) bool {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	h.status.Height = height
+	h.status.BlockTime = blockTime
+	if !h.status.Halted && h.condition.ReachedBy(height, blockTime) {
+		haltedAt := time.Now().UTC()
+		h.status.Halted = true
+		h.status.HaltedAt = &haltedAt
+	}
+	return h.status.Halted
+}
+
+func (h *Halter) IsHalted() bool {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+	return h.status.Halted
+}
+
+func (h *Halter) Status() SyncStatus {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+	return h.status
+}
+
+// RegisterRESTRoute exposes Status under EndpointGETSyncStatus
+func (h *Halter) RegisterRESTRoute(router *mux.Router) {
+	router.HandleFunc(EndpointGETSyncStatus, func(writer http.ResponseWriter, request *http.Request) {
+		statusJSON, err := json.Marshal(h.Status())
+		if err != nil {
+			http.Error(writer, err.Error(), 500)
+			return
+		}
+		writer.WriteHeader(200)
+		writer.Write(statusJSON)
+	}).Methods("GET")
+}
@@ -0,0 +1,37 @@
+package mantlemint
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cometbft/cometbft/state"
+	tendermint "github.com/cometbft/cometbft/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHalter(t *testing.T) {
+	genesisTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	block := func(height int64) *tendermint.Block {
+		return &tendermint.Block{Header: tendermint.Header{Height: height, Time: genesisTime.Add(time.Duration(height) * time.Minute)}}
+	}
+
+	halter := NewHalter(HaltCondition{Height: 10})
+	assert.False(t, halter.Resume(state.State{}))
+	assert.False(t, halter.Commit(block(9)))
+	assert.True(t, halter.Commit(block(10)))
+	assert.True(t, halter.IsHalted())
+	assert.Equal(t, int64(10), halter.Status().Height)
+	assert.NotNil(t, halter.Status().HaltedAt)
+
+	// first block at or past halt time
+	halter = NewHalter(HaltCondition{Time: genesisTime.Add(90 * time.Second)})
+	assert.False(t, halter.Commit(block(1)))
+	assert.True(t, halter.Commit(block(2)))
+
+	// restarted past halt height
+	halter = NewHalter(HaltCondition{Height: 10})
+	assert.True(t, halter.Resume(state.State{LastBlockHeight: 12}))
+
+	// disabled
+	assert.False(t, NewHalter(HaltCondition{}).Commit(block(1000)))
+}
@@ -104,6 +104,18 @@ func (mm *Instance) Init(genesis *tendermint.GenesisDoc) error {
 	return nil
 }
 
+// LastBlockHeight returns the height of the last block injected into db, 0 if none
+func LastBlockHeight(db dbm.DB) (int64, error) {
+	stateStore := state.NewStore(wrapped.NewWrappedDB(db), state.StoreOptions{
+		DiscardABCIResponses: false,
+	})
+	lastState, err := stateStore.Load()
+	if err != nil {
+		return 0, err
+	}
+	return lastState.LastBlockHeight, nil
+}
+
 func (mm *Instance) LoadInitialState() error {
 	if lastState, err := mm.stateStore.Load(); err != nil {
 		return err
@@ -191,6 +203,29 @@ func (mm *Instance) GetCurrentEventCollector() *EventCollector {
 	return mm.evc
 }
 
+// LoadEventCollector rebuilds an EventCollector for an already injected block
+// from ABCI responses saved in the state store
+func (mm *Instance) LoadEventCollector(block *tendermint.Block) (*EventCollector, error) {
+	return LoadEventCollector(mm.stateStore, block)
+}
+
+// LoadEventCollector rebuilds an EventCollector for an already injected block from stateStore,
+// without a running instance
+func LoadEventCollector(stateStore state.Store, block *tendermint.Block) (*EventCollector, error) {
+	responses, err := stateStore.LoadABCIResponses(block.Height)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EventCollector{
+		Height:             block.Height,
+		Block:              block,
+		ResponseBeginBlock: responses.BeginBlock,
+		ResponseEndBlock:   responses.EndBlock,
+		ResponseDeliverTxs: responses.DeliverTxs,
+	}, nil
+}
+
 func (mm *Instance) safeRunBefore(block *tendermint.Block) error {
 	if mm.runBefore != nil {
 		return mm.runBefore(block)
@@ -200,7 +235,7 @@ func (mm *Instance) safeRunBefore(block *tendermint.Block) error {
 }
 
 func (mm *Instance) safeRunAfter(block *tendermint.Block, events *EventCollector) error {
-	if mm.runBefore != nil {
+	if mm.runAfter != nil {
 		return mm.runAfter(block, events)
 	} else {
 		return nil
@@ -13,6 +13,7 @@ type Mantlemint interface {
 	GetCurrentBlock() *tendermint.Block
 	GetCurrentState() state.State
 	GetCurrentEventCollector() *EventCollector
+	LoadEventCollector(*tendermint.Block) (*EventCollector, error)
 	SetBlockExecutor(executor Executor)
 }
 
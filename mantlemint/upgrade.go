@@ -0,0 +1,235 @@
+package mantlemint
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/state"
+	tendermint "github.com/cometbft/cometbft/types"
+	servertypes "github.com/cosmos/cosmos-sdk/server/types"
+	upgradetypes "github.com/cosmos/cosmos-sdk/x/upgrade/types"
+)
+
+// AppUpgrade switches to App from Height on
+type AppUpgrade struct {
+	Height int64
+	App    string
+}
+
+// AppSchedule maps heights to the app executing them.
+// Software upgrades change the state machine, so every block has to be replayed
+// by the app version the chain ran at its height.
+type AppSchedule struct {
+	initial  string
+	upgrades []AppUpgrade
+}
+
+// NewAppSchedule runs initial from genesis, then each of upgrades from its height on
+func NewAppSchedule(initial string, upgrades []AppUpgrade) (AppSchedule, error) {
+	if initial == "" {
+		return AppSchedule{}, fmt.Errorf("initial app is required")
+	}
+
+	sorted := make([]AppUpgrade, len(upgrades))
+	copy(sorted, upgrades)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Height < sorted[j].Height })
+
+	for i, upgrade := range sorted {
+		if upgrade.Height < 1 {
+			return AppSchedule{}, fmt.Errorf("upgrade to %q: height must be positive, got %d", upgrade.App, upgrade.Height)
+		}
+		if upgrade.App == "" {
+			return AppSchedule{}, fmt.Errorf("upgrade at height %d: app is required", upgrade.Height)
+		}
+		if i > 0 && sorted[i-1].Height == upgrade.Height {
+			return AppSchedule{}, fmt.Errorf("more than one upgrade at height %d", upgrade.Height)
+		}
+	}
+
+	return AppSchedule{initial: initial, upgrades: sorted}, nil
+}
+
+// AppAt returns the app executing the block at height
+func (s AppSchedule) AppAt(height int64) string {
+	app := s.initial
+	for _, upgrade := range s.upgrades {
+		if upgrade.Height > height {
+			break
+		}
+		app = upgrade.App
+	}
+	return app
+}
+
+func (s AppSchedule) String() string {
+	ranges := []string{fmt.Sprintf("%s from genesis", s.initial)}
+	for _, upgrade := range s.upgrades {
+		ranges = append(ranges, fmt.Sprintf("%s from %d", upgrade.App, upgrade.Height))
+	}
+	return strings.Join(ranges, ", ")
+}
+
+// AppUpgradeError is returned from Inject when the block at Height is scheduled for another app.
+// The block was not applied; mantlemint has to be restarted to continue with app To.
+type AppUpgradeError struct {
+	Height int64
+	From   string
+	To     string
+}
+
+func (e *AppUpgradeError) Error() string {
+	return fmt.Sprintf(
+		"height %d is scheduled for app %q, running %q; restart mantlemint to continue with %q",
+		e.Height,
+		e.To,
+		e.From,
+		e.To,
+	)
+}
+
+// UpgradeNeededError is returned from Inject when an x/upgrade plan takes effect at Height,
+// which the running app has no handler for, and the app schedule doesn't switch apps there
+type UpgradeNeededError struct {
+	Height int64
+	App    string
+	Plan   upgradetypes.Plan
+}
+
+func (e *UpgradeNeededError) Error() string {
+	return fmt.Sprintf(
+		"upgrade %q needed at height %d, which app %q can't apply; schedule the upgraded app with [[upgrades]] height = %d",
+		e.Plan.Name,
+		e.Height,
+		e.App,
+		e.Height,
+	)
+}
+
+// UpgradeHandlerChecker is implemented by an AppFactory whose apps can tell
+// whether they apply an x/upgrade plan
+type UpgradeHandlerChecker interface {
+	HasUpgradeHandler(app servertypes.Application, name string) bool
+}
+
+// UpgradePlanner reads x/upgrade state of the running app
+type UpgradePlanner interface {
+	// CurrentPlan returns the pending upgrade plan, nil if there is none
+	CurrentPlan() (*upgradetypes.Plan, error)
+	// HasHandler tells whether the running app applies the named upgrade
+	HasHandler(name string) bool
+	// DumpUpgradeInfo leaves plan for the next app, as a node does when halting for an upgrade;
+	// store migrations of the next app depend on it
+	DumpUpgradeInfo(height int64, plan upgradetypes.Plan) error
+}
+
+type appUpgradePlanner struct {
+	app     servertypes.Application
+	factory AppFactory
+	home    string
+}
+
+// NewUpgradePlanner creates an UpgradePlanner over app, created by factory with home
+func NewUpgradePlanner(app servertypes.Application, factory AppFactory, home string) UpgradePlanner {
+	return &appUpgradePlanner{
+		app:     app,
+		factory: factory,
+		home:    home,
+	}
+}
+
+func (p *appUpgradePlanner) CurrentPlan() (*upgradetypes.Plan, error) {
+	req, err := (&upgradetypes.QueryCurrentPlanRequest{}).Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	res := p.app.Query(abci.RequestQuery{
+		Path: "/cosmos.upgrade.v1beta1.Query/CurrentPlan",
+		Data: req,
+	})
+	if !res.IsOK() {
+		return nil, fmt.Errorf("failed to query upgrade plan: %s", res.Log)
+	}
+
+	var current upgradetypes.QueryCurrentPlanResponse
+	if err := current.Unmarshal(res.Value); err != nil {
+		return nil, err
+	}
+	return current.Plan, nil
+}
+
+func (p *appUpgradePlanner) HasHandler(name string) bool {
+	checker, ok := p.factory.(UpgradeHandlerChecker)
+	if !ok {
+		// can't tell; leave it to the app
+		return true
+	}
+	return checker.HasUpgradeHandler(p.app, name)
+}
+
+func (p *appUpgradePlanner) DumpUpgradeInfo(height int64, plan upgradetypes.Plan) error {
+	dir := filepath.Join(p.home, "data")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	// same as x/upgrade keeper does on halt
+	info, err := json.Marshal(upgradetypes.Plan{
+		Name:   plan.Name,
+		Height: height,
+		Info:   plan.Info,
+	})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, upgradetypes.UpgradeInfoFilename), info, 0o600)
+}
+
+// NewUpgradeGuard creates a MantlemintCallbackBefore that stops before a block the running app
+// must not execute: a block scheduled for another app, or a block where an x/upgrade plan
+// takes effect that the running app can't apply.
+func NewUpgradeGuard(
+	getState func() state.State,
+	schedule AppSchedule,
+	running string,
+	planner UpgradePlanner,
+) MantlemintCallbackBefore {
+	return func(block *tendermint.Block) error {
+		// nothing is committed yet to hold a plan
+		var plan *upgradetypes.Plan
+		if getState().LastBlockHeight > 0 {
+			current, err := planner.CurrentPlan()
+			if err != nil {
+				return err
+			}
+			if current != nil && current.Height == block.Height {
+				plan = current
+			}
+		}
+
+		if next := schedule.AppAt(block.Height); next != running {
+			if plan != nil {
+				if err := planner.DumpUpgradeInfo(block.Height, *plan); err != nil {
+					return err
+				}
+			}
+			return &AppUpgradeError{Height: block.Height, From: running, To: next}
+		}
+
+		if plan == nil {
+			return nil
+		}
+		if !planner.HasHandler(plan.Name) {
+			return &UpgradeNeededError{Height: block.Height, App: running, Plan: *plan}
+		}
+
+		log.Printf("[mantlemint/upgrade] applying upgrade %q at height %d with app %q", plan.Name, block.Height, running)
+		return nil
+	}
+}
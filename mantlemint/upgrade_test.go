@@ -0,0 +1,70 @@
+package mantlemint
+
+import (
+	"testing"
+
+	"github.com/cometbft/cometbft/state"
+	tendermint "github.com/cometbft/cometbft/types"
+	upgradetypes "github.com/cosmos/cosmos-sdk/x/upgrade/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeUpgradePlanner struct {
+	plan     *upgradetypes.Plan
+	handlers map[string]bool
+	dumped   *upgradetypes.Plan
+}
+
+func (p *fakeUpgradePlanner) CurrentPlan() (*upgradetypes.Plan, error) { return p.plan, nil }
+func (p *fakeUpgradePlanner) HasHandler(name string) bool              { return p.handlers[name] }
+func (p *fakeUpgradePlanner) DumpUpgradeInfo(height int64, plan upgradetypes.Plan) error {
+	plan.Height = height
+	p.dumped = &plan
+	return nil
+}
+
+func TestAppSchedule(t *testing.T) {
+	schedule, err := NewAppSchedule("v1", []AppUpgrade{{Height: 200, App: "v3"}, {Height: 100, App: "v2"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", schedule.AppAt(1))
+	assert.Equal(t, "v1", schedule.AppAt(99))
+	assert.Equal(t, "v2", schedule.AppAt(100))
+	assert.Equal(t, "v3", schedule.AppAt(1000))
+
+	_, err = NewAppSchedule("v1", []AppUpgrade{{Height: 100, App: "v2"}, {Height: 100, App: "v3"}})
+	assert.NotNil(t, err)
+	_, err = NewAppSchedule("v1", []AppUpgrade{{Height: 0, App: "v2"}})
+	assert.NotNil(t, err)
+}
+
+func TestUpgradeGuard(t *testing.T) {
+	schedule, _ := NewAppSchedule("v1", []AppUpgrade{{Height: 100, App: "v2"}})
+	getState := func() state.State { return state.State{LastBlockHeight: 49} }
+	block := func(height int64) *tendermint.Block {
+		return &tendermint.Block{Header: tendermint.Header{Height: height}}
+	}
+
+	planner := &fakeUpgradePlanner{handlers: map[string]bool{"handled": true}}
+	guard := NewUpgradeGuard(getState, schedule, "v1", planner)
+	assert.Nil(t, guard(block(50)))
+
+	// plan the running app handles
+	planner.plan = &upgradetypes.Plan{Name: "handled", Height: 50}
+	assert.Nil(t, guard(block(50)))
+
+	// plan the running app can't apply
+	planner.plan = &upgradetypes.Plan{Name: "unknown", Height: 50}
+	var upgradeNeededErr *UpgradeNeededError
+	assert.ErrorAs(t, guard(block(50)), &upgradeNeededErr)
+	assert.Equal(t, int64(50), upgradeNeededErr.Height)
+
+	// scheduled app switch along with a plan; info is left for the next app
+	planner.plan = &upgradetypes.Plan{Name: "v2", Height: 100}
+	var appUpgradeErr *AppUpgradeError
+	assert.ErrorAs(t, guard(block(100)), &appUpgradeErr)
+	assert.Equal(t, "v2", appUpgradeErr.To)
+	assert.Equal(t, "v2", planner.dumped.Name)
+
+	// the next app runs past the switch
+	assert.Nil(t, NewUpgradeGuard(getState, schedule, "v2", &fakeUpgradePlanner{})(block(100)))
+}
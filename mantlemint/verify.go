@@ -0,0 +1,176 @@
+package mantlemint
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+
+	"github.com/cometbft/cometbft/state"
+	tendermint "github.com/cometbft/cometbft/types"
+)
+
+// ResultsHashPolicy decides what happens when locally computed results
+// don't match LastResultsHash of the next block header
+type ResultsHashPolicy string
+
+const (
+	// ResultsHashPolicyLog only logs the mismatch and keeps syncing
+	ResultsHashPolicyLog ResultsHashPolicy = "log"
+	// ResultsHashPolicyHalt stops syncing before injecting the next block
+	ResultsHashPolicyHalt ResultsHashPolicy = "halt"
+	// ResultsHashPolicyRollback reverts the diverged block, then stops syncing
+	ResultsHashPolicyRollback ResultsHashPolicy = "rollback"
+)
+
+func (p ResultsHashPolicy) Validate() error {
+	switch p {
+	case ResultsHashPolicyLog, ResultsHashPolicyHalt, ResultsHashPolicyRollback:
+		return nil
+	default:
+		return fmt.Errorf("unknown results hash policy %q; expected one of log, halt, rollback", p)
+	}
+}
+
+// ResultsHashMismatchError is returned from Inject when the block at Height
+// was executed with results different from the chain
+type ResultsHashMismatchError struct {
+	Height   int64
+	Expected []byte
+	Actual   []byte
+	Policy   ResultsHashPolicy
+}
+
+func (e *ResultsHashMismatchError) Error() string {
+	return fmt.Sprintf(
+		"results hash mismatch at height %d; expected=%X, actual=%X",
+		e.Height,
+		e.Expected,
+		e.Actual,
+	)
+}
+
+// NewResultsHashVerifier creates a MantlemintCallbackBefore that compares LastResultsHash
+// of the current state (results of the last injected block) against the header of the block
+// about to be injected, which carries the results hash agreed on by the chain.
+func NewResultsHashVerifier(getState func() state.State, policy ResultsHashPolicy) MantlemintCallbackBefore {
+	return func(block *tendermint.Block) error {
+		lastState := getState()
+
+		// nothing to compare against for the first block,
+		// or if the next block doesn't directly follow the last one
+		if block.Height <= lastState.InitialHeight || lastState.LastBlockHeight != block.Height-1 {
+			return nil
+		}
+
+		if bytes.Equal(lastState.LastResultsHash, block.Header.LastResultsHash) {
+			return nil
+		}
+
+		mismatchErr := &ResultsHashMismatchError{
+			Height:   lastState.LastBlockHeight,
+			Expected: block.Header.LastResultsHash,
+			Actual:   lastState.LastResultsHash,
+			Policy:   policy,
+		}
+
+		log.Printf("[mantlemint/verify] %v", mismatchErr)
+		if policy == ResultsHashPolicyLog {
+			return nil
+		}
+
+		return mismatchErr
+	}
+}
+
+// BlockVerificationError is returned from Inject when the incoming block
+// cannot be verified against the validator set tracked in state
+type BlockVerificationError struct {
+	Height int64
+	Reason error
+}
+
+func (e *BlockVerificationError) Error() string {
+	return fmt.Sprintf("block %d failed verification: %v", e.Height, e.Reason)
+}
+
+func (e *BlockVerificationError) Unwrap() error {
+	return e.Reason
+}
+
+// CommitSource returns the commit signing the block at height
+type CommitSource func(height int64) (*tendermint.Commit, error)
+
+// NewCommitVerifier creates a MantlemintCallbackBefore that verifies the incoming block
+// the way a light client would, before it gets injected:
+//   - header must point at the last injected block, with the validator sets tracked in state
+//   - LastCommit must carry signatures of 2/3+ voting power of the validators of the last block
+//   - the commit of the block itself, from commitOf, must carry signatures of 2/3+ voting power
+//     of the validators of the block
+//
+// LastCommit alone would leave the block itself unverified until the next one arrives;
+// its own commit makes sure nothing but a block signed by the chain gets injected.
+func NewCommitVerifier(getState func() state.State, chainID string, commitOf CommitSource) MantlemintCallbackBefore {
+	return func(block *tendermint.Block) error {
+		lastState := getState()
+		fail := func(reason error) error {
+			return &BlockVerificationError{Height: block.Height, Reason: reason}
+		}
+
+		// txs, last commit and evidence must match the header, which is what commits sign
+		if err := block.ValidateBasic(); err != nil {
+			return fail(err)
+		}
+		if block.ChainID != chainID {
+			return fail(fmt.Errorf("wrong chain id %s, expected %s", block.ChainID, chainID))
+		}
+
+		// validator sets are only known for the block right after the last one
+		if lastState.LastBlockHeight != block.Height-1 && block.Height != lastState.InitialHeight {
+			return fail(fmt.Errorf("expected block %d", lastState.LastBlockHeight+1))
+		}
+		if !bytes.Equal(block.ValidatorsHash, lastState.Validators.Hash()) {
+			return fail(fmt.Errorf("wrong validators hash %X, expected %X", block.ValidatorsHash, lastState.Validators.Hash()))
+		}
+		if !bytes.Equal(block.NextValidatorsHash, lastState.NextValidators.Hash()) {
+			return fail(fmt.Errorf("wrong next validators hash %X, expected %X", block.NextValidatorsHash, lastState.NextValidators.Hash()))
+		}
+
+		// the first block carries no last commit
+		if block.Height != lastState.InitialHeight {
+			if !bytes.Equal(block.LastBlockID.Hash, lastState.LastBlockID.Hash) {
+				return fail(fmt.Errorf("wrong last block hash %X, expected %X", block.LastBlockID.Hash, lastState.LastBlockID.Hash))
+			}
+			if err := lastState.LastValidators.VerifyCommitLight(chainID, block.LastBlockID, block.Height-1, block.LastCommit); err != nil {
+				return fail(err)
+			}
+		}
+
+		commit, commitErr := commitOf(block.Height)
+		if commitErr != nil {
+			return fail(fmt.Errorf("no commit: %w", commitErr))
+		}
+		if !bytes.Equal(commit.BlockID.Hash, block.Hash()) {
+			return fail(fmt.Errorf("commit signs block hash %X, expected %X", commit.BlockID.Hash, block.Hash()))
+		}
+		if err := lastState.Validators.VerifyCommitLight(chainID, commit.BlockID, block.Height, commit); err != nil {
+			return fail(err)
+		}
+
+		return nil
+	}
+}
+
+// ChainCallbackBefore runs callbacks in order, stopping at the first error
+func ChainCallbackBefore(callbacks ...MantlemintCallbackBefore) MantlemintCallbackBefore {
+	return func(block *tendermint.Block) error {
+		for _, callback := range callbacks {
+			if callback == nil {
+				continue
+			}
+			if err := callback(block); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
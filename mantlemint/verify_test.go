@@ -0,0 +1,187 @@
+package mantlemint
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cometbft/cometbft/crypto/tmhash"
+	cmtproto "github.com/cometbft/cometbft/proto/tendermint/types"
+	"github.com/cometbft/cometbft/state"
+	tendermint "github.com/cometbft/cometbft/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultsHashVerifier(t *testing.T) {
+	lastState := state.State{
+		InitialHeight:   1,
+		LastBlockHeight: 10,
+		LastResultsHash: []byte{1},
+	}
+	block := func(height int64, lastResultsHash []byte) *tendermint.Block {
+		return &tendermint.Block{Header: tendermint.Header{Height: height, LastResultsHash: lastResultsHash}}
+	}
+
+	for name, tc := range map[string]struct {
+		state    state.State
+		block    *tendermint.Block
+		policy   ResultsHashPolicy
+		mismatch bool
+	}{
+		"match": {
+			state:  lastState,
+			block:  block(11, []byte{1}),
+			policy: ResultsHashPolicyHalt,
+		},
+		"mismatch, log": {
+			state:  lastState,
+			block:  block(11, []byte{2}),
+			policy: ResultsHashPolicyLog,
+		},
+		"mismatch, halt": {
+			state:    lastState,
+			block:    block(11, []byte{2}),
+			policy:   ResultsHashPolicyHalt,
+			mismatch: true,
+		},
+		"mismatch, rollback": {
+			state:    lastState,
+			block:    block(11, []byte{2}),
+			policy:   ResultsHashPolicyRollback,
+			mismatch: true,
+		},
+		"first block": {
+			state:  state.State{InitialHeight: 1, LastResultsHash: []byte{1}},
+			block:  block(1, []byte{2}),
+			policy: ResultsHashPolicyHalt,
+		},
+		"non-contiguous height": {
+			state:  lastState,
+			block:  block(13, []byte{2}),
+			policy: ResultsHashPolicyHalt,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			verify := NewResultsHashVerifier(func() state.State { return tc.state }, tc.policy)
+			err := verify(tc.block)
+			if !tc.mismatch {
+				assert.Nil(t, err)
+				return
+			}
+
+			var mismatchErr *ResultsHashMismatchError
+			assert.True(t, errors.As(err, &mismatchErr))
+			assert.Equal(t, int64(10), mismatchErr.Height)
+			assert.Equal(t, tc.policy, mismatchErr.Policy)
+			assert.Equal(t, []byte{2}, mismatchErr.Expected)
+			assert.Equal(t, []byte{1}, mismatchErr.Actual)
+		})
+	}
+}
+
+func TestCommitVerifier(t *testing.T) {
+	const chainID = "test-chain"
+	validators, signers := tendermint.RandValidatorSet(4, 10)
+	otherValidators, _ := tendermint.RandValidatorSet(4, 10)
+
+	signCommit := func(blockID tendermint.BlockID, height int64) *tendermint.Commit {
+		voteSet := tendermint.NewVoteSet(chainID, height, 0, cmtproto.PrecommitType, validators)
+		commit, err := tendermint.MakeCommit(blockID, height, 0, voteSet, signers, time.Now())
+		assert.Nil(t, err)
+		return commit
+	}
+	blockIDOf := func(name string) tendermint.BlockID {
+		return tendermint.BlockID{Hash: tmhash.Sum([]byte(name)), PartSetHeader: tendermint.PartSetHeader{Total: 1, Hash: tmhash.Sum([]byte(name + " parts"))}}
+	}
+
+	// state after block 9, signed by validators
+	lastBlockID := blockIDOf("block 9")
+	lastState := state.State{
+		ChainID:         chainID,
+		InitialHeight:   1,
+		LastBlockHeight: 9,
+		LastBlockID:     lastBlockID,
+		LastValidators:  validators,
+		Validators:      validators,
+		NextValidators:  validators,
+	}
+
+	makeBlock := func(txs []tendermint.Tx, lastBlockID tendermint.BlockID, validatorsHash []byte) (*tendermint.Block, tendermint.BlockID) {
+		block := tendermint.MakeBlock(10, txs, signCommit(lastBlockID, 9), nil)
+		block.ChainID = chainID
+		block.LastBlockID = lastBlockID
+		block.ValidatorsHash = validatorsHash
+		block.NextValidatorsHash = validators.Hash()
+		block.ProposerAddress = validators.Proposer.Address
+		partSet, err := block.MakePartSet(tendermint.BlockPartSizeBytes)
+		assert.Nil(t, err)
+		return block, tendermint.BlockID{Hash: block.Hash(), PartSetHeader: partSet.Header()}
+	}
+
+	block, blockID := makeBlock([]tendermint.Tx{tendermint.Tx("tx")}, lastBlockID, validators.Hash())
+	commit := signCommit(blockID, 10)
+
+	// a genuine last commit, but txs nobody signed
+	forgedTxs, _ := makeBlock([]tendermint.Tx{tendermint.Tx("forged")}, lastBlockID, validators.Hash())
+	forgedParent, forgedParentID := makeBlock([]tendermint.Tx{tendermint.Tx("tx")}, blockIDOf("forged block 9"), validators.Hash())
+	wrongValidators, wrongValidatorsID := makeBlock([]tendermint.Tx{tendermint.Tx("tx")}, lastBlockID, otherValidators.Hash())
+
+	// signatures of 2 out of 4 validators
+	weakCommit := signCommit(blockID, 10)
+	weakCommit.Signatures[2] = tendermint.NewCommitSigAbsent()
+	weakCommit.Signatures[3] = tendermint.NewCommitSigAbsent()
+
+	for name, tc := range map[string]struct {
+		block  *tendermint.Block
+		commit *tendermint.Commit
+		err    string
+	}{
+		"valid": {
+			block:  block,
+			commit: commit,
+		},
+		"forged last block id": {
+			block:  forgedParent,
+			commit: signCommit(forgedParentID, 10),
+			err:    "wrong last block hash",
+		},
+		"wrong validators hash": {
+			block:  wrongValidators,
+			commit: signCommit(wrongValidatorsID, 10),
+			err:    "wrong validators hash",
+		},
+		"commit under 2/3 voting power": {
+			block:  block,
+			commit: weakCommit,
+			err:    "insufficient voting power",
+		},
+		"forged txs with a valid last commit": {
+			block:  forgedTxs,
+			commit: commit,
+			err:    "commit signs block hash",
+		},
+		"no commit": {
+			block: block,
+			err:   "no commit",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			verify := NewCommitVerifier(func() state.State { return lastState }, chainID, func(height int64) (*tendermint.Commit, error) {
+				if tc.commit == nil {
+					return nil, fmt.Errorf("commit %d not found", height)
+				}
+				return tc.commit, nil
+			})
+
+			err := verify(tc.block)
+			if tc.err == "" {
+				assert.Nil(t, err)
+				return
+			}
+			var verificationErr *BlockVerificationError
+			assert.True(t, errors.As(err, &verificationErr))
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	tmlog "github.com/cometbft/cometbft/libs/log"
+	"github.com/cometbft/cometbft/proxy"
+	"github.com/cometbft/cometbft/state"
+	"github.com/cometbft/cometbft/store"
+	tendermint "github.com/cometbft/cometbft/types"
+	"github.com/cosmos/cosmos-sdk/baseapp"
+	servertypes "github.com/cosmos/cosmos-sdk/server/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/spf13/viper"
+	blockFeeder "github.com/terra-money/mantlemint/block_feed"
+	"github.com/terra-money/mantlemint/chains/terra"
+	"github.com/terra-money/mantlemint/config"
+	"github.com/terra-money/mantlemint/db/heleveldb"
+	"github.com/terra-money/mantlemint/db/hememdb"
+	"github.com/terra-money/mantlemint/db/hepebble"
+	"github.com/terra-money/mantlemint/db/hld"
+	"github.com/terra-money/mantlemint/db/safe_batch"
+	"github.com/terra-money/mantlemint/db/wrapped"
+	"github.com/terra-money/mantlemint/indexer"
+	"github.com/terra-money/mantlemint/indexer/block"
+	"github.com/terra-money/mantlemint/indexer/tx"
+	"github.com/terra-money/mantlemint/mantlemint"
+	"github.com/terra-money/mantlemint/store/rootmulti"
+
+	dbm "github.com/cometbft/cometbft-db"
+)
+
+// node is the db stack, app and mantlemint instance shared by `start` and admin commands
+type node struct {
+	ldb           *heleveldb.Driver
+	hldb          *hld.HeightLimitedDB
+	batched       dbm.DB
+	batchedOrigin safe_batch.SafeBatchDBCloser
+
+	factory    mantlemint.AppFactory
+	codec      mantlemint.EncodingConfig
+	app        servertypes.Application
+	appCreator proxy.ClientCreator
+	appConns   proxy.AppConns
+	mm         mantlemint.Mantlemint
+}
+
+// nodeCallbacks builds Inject callbacks; getState returns the state of the last injected block
+type nodeCallbacks func(getState func() state.State) (mantlemint.MantlemintCallbackBefore, mantlemint.MantlemintCallbackAfter)
+
+// appFactories are the chains mantlemint can run, selectable with `app`
+var appFactories = map[string]func() mantlemint.AppFactory{
+	"terra-classic": terra.NewAppFactory,
+}
+
+func init() {
+	for name := range appFactories {
+		config.Apps = append(config.Apps, name)
+	}
+	sort.Strings(config.Apps)
+}
+
+func newAppFactory(name string) (mantlemint.AppFactory, error) {
+	newFactory, ok := appFactories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown app %q; available: %s", name, strings.Join(config.Apps, ", "))
+	}
+	return newFactory(), nil
+}
+
+// configuredAppFactory returns the factory of the app scheduled at height, with the SDK configured for it
+func configuredAppFactory(mantlemintConfig config.Config, height int64) (mantlemint.AppFactory, error) {
+	schedule, scheduleErr := mantlemint.NewAppSchedule(mantlemintConfig.App, mantlemintConfig.Upgrades)
+	if scheduleErr != nil {
+		return nil, scheduleErr
+	}
+	factory, factoryErr := newAppFactory(schedule.AppAt(height))
+	if factoryErr != nil {
+		return nil, factoryErr
+	}
+	configureSDK(factory)
+	return factory, nil
+}
+
+// configureSDK sets bech32 prefixes and the like of the app, once per process
+func configureSDK(factory mantlemint.AppFactory) {
+	sdkConfig := sdk.GetConfig()
+	factory.ConfigureSDK(sdkConfig)
+	sdkConfig.Seal()
+}
+
+// openDB opens mantlemint db without anything on top of it
+func openDB(mantlemintConfig config.Config) *heleveldb.Driver {
+	session, sessionErr := openSessionDB(mantlemintConfig, mantlemintConfig.MantlemintDB)
+	if sessionErr != nil {
+		panic(sessionErr)
+	}
+	driverConfig := &heleveldb.DriverConfig{
+		Mode: heleveldb.DriverModeKeySuffixDesc,
+
+		RollbackJournalSize: mantlemintConfig.RollbackJournalSize,
+	}
+	if mantlemintConfig.TieringColdDir != "" {
+		driverConfig.Cold = &heleveldb.ColdConfig{
+			Dir:           mantlemintConfig.TieringColdDir,
+			Name:          mantlemintConfig.MantlemintDB,
+			PartitionSize: mantlemintConfig.TieringPartitionSize,
+			KeepHot:       mantlemintConfig.TieringKeepHot,
+		}
+	}
+	return heleveldb.NewDriver(session, driverConfig)
+}
+
+// openSessionDB opens name under home with the backend of mantlemint db
+func openSessionDB(mantlemintConfig config.Config, name string) (dbm.DB, error) {
+	switch mantlemintConfig.DBBackend {
+	case config.DBBackendPebble:
+		return hepebble.NewPebbleDB(name, mantlemintConfig.Home)
+	case config.DBBackendMemDB:
+		return hememdb.NewMemDB(), nil
+	default:
+		return dbm.NewGoLevelDB(name, mantlemintConfig.Home)
+	}
+}
+
+// storeView is mantlemint db opened for admin commands that only read it:
+// no app, no genesis and no write height
+type storeView struct {
+	ldb        *heleveldb.Driver
+	db         *hld.HeightLimitedDB
+	stateStore state.Store
+	blockStore *store.BlockStore
+}
+
+// openStoreView opens mantlemint db with the state and block store in it, read at the latest height
+func openStoreView(mantlemintConfig config.Config) *storeView {
+	ldb := openDB(mantlemintConfig)
+	hldb := hld.ApplyHeightLimitedDB(ldb, &hld.HeightLimitedDBConfig{})
+	wdb := wrapped.NewWrappedDB(hldb)
+
+	return &storeView{
+		ldb: ldb,
+		db:  hldb,
+		stateStore: state.NewStore(wdb, state.StoreOptions{
+			DiscardABCIResponses: false,
+		}),
+		blockStore: store.NewBlockStore(wdb),
+	}
+}
+
+func (v *storeView) loadEventCollector(block *tendermint.Block) (*mantlemint.EventCollector, error) {
+	return mantlemint.LoadEventCollector(v.stateStore, block)
+}
+
+// Close closes mantlemint db
+func (v *storeView) Close() error {
+	return v.db.Close()
+}
+
+// newNode brings up the app and mantlemint instance over mantlemint db,
+// initializing the chain from genesis if the db is empty.
+// callbacks may be nil.
+func newNode(mantlemintConfig config.Config, callbacks nodeCallbacks) *node {
+	ldb := openDB(mantlemintConfig)
+	hldb := hld.ApplyHeightLimitedDB(
+		ldb,
+		&hld.HeightLimitedDBConfig{
+			Debug: true,
+		},
+	)
+
+	batched := safe_batch.NewSafeBatchDB(hldb)
+	batchedOrigin := batched.(safe_batch.SafeBatchDBCloser)
+
+	genesisDoc := getGenesisDoc(mantlemintConfig.GenesisPath)
+
+	// run the app the chain ran at the next height
+	schedule, scheduleErr := mantlemint.NewAppSchedule(mantlemintConfig.App, mantlemintConfig.Upgrades)
+	if scheduleErr != nil {
+		panic(scheduleErr)
+	}
+	lastHeight, lastHeightErr := mantlemint.LastBlockHeight(batched)
+	if lastHeightErr != nil {
+		panic(lastHeightErr)
+	}
+	nextHeight := lastHeight + 1
+	if lastHeight == 0 {
+		nextHeight = genesisDoc.InitialHeight
+	}
+	appName := schedule.AppAt(nextHeight)
+	log.Printf("[app] running %s from height %d; schedule: %s", appName, nextHeight, schedule)
+
+	factory, factoryErr := newAppFactory(appName)
+	if factoryErr != nil {
+		panic(factoryErr)
+	}
+	configureSDK(factory)
+	logger := tmlog.NewTMLogger(os.Stdout)
+	codec := factory.EncodingConfig()
+
+	// customize CMS to limit kv store's read height on query
+	cms := rootmulti.NewStore(batched, logger, hldb)
+	// app.toml is merged into the global viper by config.NewConfig
+	appOpts := newAppOptions(mantlemintConfig, viper.GetViper())
+
+	app := factory.NewApp(
+		logger,
+		batched,
+		mantlemintConfig.Home,
+		appOpts,
+		fauxMerkleModeOpt,
+		func(ba *baseapp.BaseApp) {
+			ba.SetCMS(cms)
+		},
+		baseapp.SetChainID(mantlemintConfig.ChainID),
+	)
+
+	// create app...
+	appCreator := mantlemint.NewConcurrentQueryClientCreator(app)
+	appMetrics := proxy.NopMetrics()
+	appConns := proxy.NewAppConns(appCreator, appMetrics)
+	appConns.SetLogger(logger)
+	if startErr := appConns.OnStart(); startErr != nil {
+		panic(startErr)
+	}
+
+	go func() {
+		a := <-appConns.Quit()
+		fmt.Println(a)
+	}()
+
+	executor := mantlemint.NewMantlemintExecutor(batched, appConns.Consensus())
+
+	var mm mantlemint.Mantlemint
+	var runBefore mantlemint.MantlemintCallbackBefore
+	var runAfter mantlemint.MantlemintCallbackAfter
+	getState := func() state.State { return mm.GetCurrentState() }
+	if callbacks != nil {
+		runBefore, runAfter = callbacks(getState)
+	}
+
+	// stop before blocks of another app, or of an upgrade the app can't apply
+	upgradeGuard := mantlemint.NewUpgradeGuard(
+		getState,
+		schedule,
+		appName,
+		mantlemint.NewUpgradePlanner(app, factory, mantlemintConfig.Home),
+	)
+	runBefore = mantlemint.ChainCallbackBefore(runBefore, upgradeGuard)
+
+	mm = mantlemint.NewMantlemint(
+		batched,
+		appConns,
+		executor,
+		runBefore,
+		runAfter,
+	)
+
+	// initialize using provided genesis
+	initialHeight := genesisDoc.InitialHeight
+
+	// set target initial write height to genesis.initialHeight;
+	// this is safe as upon Inject it will be set with block.Height
+	hldb.SetWriteHeight(initialHeight)
+	// genesis isn't a block to rollback; keep it out of the journal
+	resumeJournal := ldb.SuspendJournal()
+	batchedOrigin.Open()
+	resumeJournal()
+
+	// initialize state machine with genesis
+	if initErr := mm.Init(genesisDoc); initErr != nil {
+		panic(initErr)
+	}
+
+	// flush to db; panic upon error (can't proceed)
+	if rollback, flushErr := batchedOrigin.Flush(); flushErr != nil {
+		debug.PrintStack()
+		panic(flushErr)
+	} else if rollback != nil {
+		rollback.Close()
+	}
+
+	// load initial state to mantlemint
+	if loadErr := mm.LoadInitialState(); loadErr != nil {
+		panic(loadErr)
+	}
+
+	// initialization is done; clear write height
+	hldb.ClearWriteHeight()
+
+	return &node{
+		ldb:           ldb,
+		hldb:          hldb,
+		batched:       batched,
+		batchedOrigin: batchedOrigin,
+		factory:       factory,
+		codec:         codec,
+		app:           app,
+		appCreator:    appCreator,
+		appConns:      appConns,
+		mm:            mm,
+	}
+}
+
+// Close stops the app and closes mantlemint db
+func (n *node) Close() error {
+	shutdown(
+		n.appConns.Stop,
+		n.batched.Close,
+	)
+	return nil
+}
+
+// indexerService is an indexer selectable with indexer.enabled
+type indexerService struct {
+	index func(codec mantlemint.EncodingConfig) indexer.IndexFunc
+	route indexer.RESTRouteRegisterer
+}
+
+var indexerServices = map[string]indexerService{
+	"tx": {
+		index: func(codec mantlemint.EncodingConfig) indexer.IndexFunc { return tx.NewIndexTx(codec.TxConfig) },
+		route: tx.RegisterRESTRoute,
+	},
+	"block": {
+		index: func(mantlemint.EncodingConfig) indexer.IndexFunc { return block.IndexBlock },
+		route: block.RegisterRESTRoute,
+	},
+}
+
+// newIndexer opens indexer db with enabled indexer services registered
+func newIndexer(mantlemintConfig config.Config, codec mantlemint.EncodingConfig) (*indexer.Indexer, error) {
+	indexerDB, indexerDBErr := openSessionDB(mantlemintConfig, mantlemintConfig.IndexerDB)
+	if indexerDBErr != nil {
+		return nil, indexerDBErr
+	}
+	indexerInstance := indexer.NewIndexerWithDB(indexerDB)
+
+	for _, name := range mantlemintConfig.Indexers {
+		service, ok := indexerServices[name]
+		if !ok {
+			indexerInstance.Close()
+			return nil, fmt.Errorf("unknown indexer %q in indexer.enabled; available: tx, block", name)
+		}
+		indexerInstance.RegisterIndexerService(name, service.index(codec))
+	}
+
+	return indexerInstance, nil
+}
+
+// injectedBlockSource provides already injected blocks from fetcher, along with events saved in mantlemint db
+func injectedBlockSource(fetcher blockFeeder.BlockFetcher, loadEventCollector func(*tendermint.Block) (*mantlemint.EventCollector, error)) indexer.BlockSource {
+	return func(height int64) (*tendermint.Block, *tendermint.BlockID, *mantlemint.EventCollector, error) {
+		blockResult, fetchErr := fetcher.FetchBlock(height)
+		if fetchErr != nil {
+			return nil, nil, nil, fetchErr
+		}
+		evc, evcErr := loadEventCollector(blockResult.Block)
+		return blockResult.Block, blockResult.BlockID, evc, evcErr
+	}
+}
+
+// openBlockFetcher opens the block source blocks from fromHeight on are fetched from:
+// the configured blockstore or archive, otherwise every rpc endpoint with failover
+func openBlockFetcher(mantlemintConfig config.Config, fromHeight int64) (blockFeeder.BlockFetcher, error) {
+	switch {
+	case mantlemintConfig.ArchivePath != "":
+		return blockFeeder.NewArchiveSubscription(fromHeight-1, mantlemintConfig.ArchivePath)
+	case mantlemintConfig.BlockStoreDir != "":
+		return blockFeeder.NewBlockStoreSubscription(fromHeight-1, mantlemintConfig.BlockStoreDir)
+	default:
+		return blockFeeder.NewRpcSubscription(mantlemintConfig.RPCEndpoints, 1)
+	}
+}
+
+// Pass this in as an option to use a dbStoreAdapter instead of an IAVLStore for simulation speed.
+func fauxMerkleModeOpt(app *baseapp.BaseApp) {
+	app.SetFauxMerkleMode()
+}
+
+func getGenesisDoc(genesisPath string) *tendermint.GenesisDoc {
+	jsonBlob, _ := os.ReadFile(genesisPath)
+	shasum := sha1.New()
+	shasum.Write(jsonBlob)
+	sum := hex.EncodeToString(shasum.Sum(nil))
+
+	log.Printf("[sync] genesis shasum=%s", sum)
+
+	if genesis, genesisErr := tendermint.GenesisDocFromFile(genesisPath); genesisErr != nil {
+		panic(genesisErr)
+	} else {
+		return genesis
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/terra-money/mantlemint/config"
+	"github.com/terra-money/mantlemint/mantlemint"
+)
+
+func TestNewIndexerBackend(t *testing.T) {
+	for backend, onDisk := range map[string]bool{
+		config.DBBackendGoLevelDB: true,
+		config.DBBackendPebble:    true,
+		config.DBBackendMemDB:     false,
+	} {
+		t.Run(backend, func(t *testing.T) {
+			home := t.TempDir()
+			indexerInstance, err := newIndexer(config.Config{Home: home, IndexerDB: "indexer", DBBackend: backend}, mantlemint.EncodingConfig{})
+			assert.Nil(t, err)
+			defer indexerInstance.Close()
+
+			_, statErr := os.Stat(filepath.Join(home, "indexer.db"))
+			assert.Equal(t, onDisk, statErr == nil)
+
+			// pebble leaves an OPTIONS file, goleveldb never does
+			options, _ := filepath.Glob(filepath.Join(home, "indexer.db", "OPTIONS-*"))
+			assert.Equal(t, backend == config.DBBackendPebble, len(options) > 0)
+		})
+	}
+}
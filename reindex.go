@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/terra-money/mantlemint/config"
+)
+
+// newReindexCmd handles `mantlemint reindex --from <height>`.
+// It drops everything indexed from the given height on, and rebuilds it up to the current state height
+// using blocks from the configured block source and events from mantlemint db.
+func newReindexCmd() *cobra.Command {
+	var fromHeight int64
+
+	cmd := &cobra.Command{
+		Use:   "reindex",
+		Short: "Rebuild indexer db from a height up to the current state height",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			mantlemintConfig, configErr := config.NewConfig(cmd.Flags())
+			if configErr != nil {
+				return configErr
+			}
+			return reindex(mantlemintConfig, fromHeight)
+		},
+	}
+	cmd.Flags().Int64Var(&fromHeight, "from", 1, "first height to reindex")
+
+	return cmd
+}
+
+func reindex(mantlemintConfig config.Config, fromHeight int64) error {
+	if fromHeight < 1 {
+		return fmt.Errorf("[reindex] invalid height %d", fromHeight)
+	}
+
+	view := openStoreView(mantlemintConfig)
+	defer view.Close()
+
+	lastState, stateErr := view.stateStore.Load()
+	if stateErr != nil {
+		return stateErr
+	}
+	stateHeight := lastState.LastBlockHeight
+	if fromHeight > stateHeight {
+		return fmt.Errorf("[reindex] height %d is above state height %d", fromHeight, stateHeight)
+	}
+
+	// txs are decoded with the codec of the app that ran the last block
+	factory, factoryErr := configuredAppFactory(mantlemintConfig, stateHeight)
+	if factoryErr != nil {
+		return factoryErr
+	}
+	indexerInstance, indexerErr := newIndexer(mantlemintConfig, factory.EncodingConfig())
+	if indexerErr != nil {
+		return indexerErr
+	}
+	defer indexerInstance.Close()
+
+	// an indexer behind fromHeight is caught up from where it is
+	lastHeight, ok, heightErr := indexerInstance.LastHeight()
+	if heightErr != nil {
+		return heightErr
+	}
+	if !ok {
+		// keys of heights indexed without a height marker are unknown; overwrite them instead
+		if err := indexerInstance.ResetHeight(fromHeight - 1); err != nil {
+			return err
+		}
+	} else if lastHeight >= fromHeight {
+		if err := indexerInstance.Truncate(fromHeight - 1); err != nil {
+			return err
+		}
+	} else {
+		fromHeight = lastHeight + 1
+	}
+
+	blockFetcher, fetcherErr := openBlockFetcher(mantlemintConfig, fromHeight)
+	if fetcherErr != nil {
+		return fetcherErr
+	}
+	defer blockFetcher.Close()
+
+	// fetching from rpc retries until an endpoint answers; give up on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	stopFetcher := context.AfterFunc(ctx, func() { _ = blockFetcher.Close() })
+	defer stopFetcher()
+
+	log.Printf("[reindex] reindexing from height %d up to %d", fromHeight, stateHeight)
+	if err := indexerInstance.Reconcile(stateHeight, injectedBlockSource(blockFetcher, view.loadEventCollector)); err != nil {
+		return err
+	}
+
+	log.Printf("[reindex] done")
+	return nil
+}
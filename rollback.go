@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+	"github.com/terra-money/mantlemint/config"
+)
+
+// newRollbackCmd handles `mantlemint rollback --to <height>`.
+// It rewinds mantlemint db, including tendermint state, to the given height
+// using the on-disk rollback journal. Indexer db is truncated on the next start.
+func newRollbackCmd() *cobra.Command {
+	var toHeight int64
+
+	cmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Rewind mantlemint db to a previous height using the rollback journal",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			mantlemintConfig, err := config.NewConfig(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			return rollback(mantlemintConfig, toHeight)
+		},
+	}
+	cmd.Flags().Int64Var(&toHeight, "to", 0, "height to rollback to; must be covered by the rollback journal")
+	_ = cmd.MarkFlagRequired("to")
+
+	return cmd
+}
+
+func rollback(mantlemintConfig config.Config, toHeight int64) error {
+	ldb := openDB(mantlemintConfig)
+	defer ldb.Close()
+
+	lowest, highest, rangeErr := ldb.RollbackRange()
+	if rangeErr != nil {
+		return rangeErr
+	}
+	log.Printf("[rollback] journal covers heights %d..%d, rolling back to %d", lowest, highest, toHeight)
+
+	if err := ldb.RollbackTo(toHeight); err != nil {
+		return fmt.Errorf("[rollback] %w", err)
+	}
+
+	log.Printf("[rollback] done; mantlemint will resume from height %d", toHeight+1)
+	return nil
+}
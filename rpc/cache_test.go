@@ -1,10 +1,12 @@
 package rpc
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -22,11 +24,6 @@ func TestCacheBackend(t *testing.T) {
 	assert.Equal(t, 501, cached2.status)
 	assert.Equal(t, []byte("error"), cached2.body)
 
-	testReq := httptest.NewRequest(
-		"get",
-		"/test/request?param=1",
-		nil,
-	)
 	testRes := httptest.NewRecorder()
 	callCount := 0
 
@@ -36,26 +33,39 @@ func TestCacheBackend(t *testing.T) {
 		writer.Write([]byte("asdf"))
 	})
 
-	// call 3 times
-	cb.HandleCachedHTTP(testRes, testReq, handler)
-	cb.HandleCachedHTTP(testRes, testReq, handler)
-	cb.HandleCachedHTTP(testRes, testReq, handler)
-	cb.HandleCachedHTTP(testRes, testReq, handler)
-	cb.HandleCachedHTTP(testRes, testReq, handler)
-	cb.HandleCachedHTTP(testRes, testReq, handler)
+	// like net/http, the request context is done once the request is served
+	serve := func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		testReq := httptest.NewRequest(
+			"get",
+			"/test/request?param=1",
+			nil,
+		).WithContext(ctx)
+		cb.HandleCachedHTTP(testRes, testReq, handler)
+		cancel()
+	}
+
+	// call 6 times
+	for i := 0; i < 6; i++ {
+		serve()
+	}
 
 	fmt.Println(callCount)
 	assert.Equal(t, 1, callCount)
 
+	// the request in transit is forgotten once its context is done
+	assert.Eventually(t, func() bool {
+		cb.mtx.RLock()
+		defer cb.mtx.RUnlock()
+		return len(cb.resultChan) == 0
+	}, time.Second, time.Millisecond)
+
 	cb.Purge()
 
 	callCount = 0
-	cb.HandleCachedHTTP(testRes, testReq, handler)
-	cb.HandleCachedHTTP(testRes, testReq, handler)
-	cb.HandleCachedHTTP(testRes, testReq, handler)
-	cb.HandleCachedHTTP(testRes, testReq, handler)
-	cb.HandleCachedHTTP(testRes, testReq, handler)
-	cb.HandleCachedHTTP(testRes, testReq, handler)
+	for i := 0; i < 6; i++ {
+		serve()
+	}
 
 	fmt.Println(callCount)
 	assert.Equal(t, callCount, 1)
@@ -0,0 +1,55 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+
+	abcicli "github.com/cometbft/cometbft/abci/client"
+	abci "github.com/cometbft/cometbft/abci/types"
+	rpcclient "github.com/cometbft/cometbft/rpc/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/terra-money/mantlemint/db/heleveldb"
+	"github.com/terra-money/mantlemint/db/hememdb"
+	"github.com/terra-money/mantlemint/db/hld"
+)
+
+// historicalApp answers queries of a key with its value at the queried height
+type historicalApp struct {
+	abci.BaseApplication
+	hldb *hld.HeightLimitedDB
+}
+
+func (app *historicalApp) Query(req abci.RequestQuery) abci.ResponseQuery {
+	value, err := app.hldb.BranchHeightLimitedDB(req.Height).Get(req.Data)
+	if err != nil {
+		return abci.ResponseQuery{Code: 1, Log: err.Error()}
+	}
+	return abci.ResponseQuery{Key: req.Data, Value: value, Height: req.Height}
+}
+
+func TestRpcClientQuery(t *testing.T) {
+	driver := hememdb.NewMemDBDriver(&heleveldb.DriverConfig{Mode: heleveldb.DriverModeKeySuffixDesc})
+	// heights are written in order, the way blocks are injected
+	for _, write := range []struct {
+		height int64
+		value  string
+	}{{1, "one"}, {3, "three"}} {
+		batch := driver.NewBatch(write.height)
+		assert.Nil(t, batch.Set([]byte("key"), []byte(write.value)))
+		assert.Nil(t, batch.Write())
+		batch.Close()
+	}
+
+	app := &historicalApp{hldb: hld.ApplyHeightLimitedDB(driver, &hld.HeightLimitedDBConfig{})}
+	client := NewRpcClient(abcicli.NewLocalClient(nil, app))
+
+	for height, expected := range map[int64]string{1: "one", 2: "one", 3: "three", 0: "three"} {
+		res, err := client.ABCIQueryWithOptions(context.Background(), "/key", []byte("key"), rpcclient.ABCIQueryOptions{Height: height})
+		assert.Nil(t, err)
+		assert.Equal(t, expected, string(res.Response.Value), "height %d", height)
+	}
+
+	res, err := client.ABCIQuery(context.Background(), "/key", []byte("key"))
+	assert.Nil(t, err)
+	assert.Equal(t, "three", string(res.Response.Value))
+}
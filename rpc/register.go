@@ -7,8 +7,6 @@ import (
 	"strconv"
 	"time"
 
-	terra "github.com/classic-terra/core/v3/app"
-	"github.com/classic-terra/core/v3/app/params"
 	tmlog "github.com/cometbft/cometbft/libs/log"
 	rpcclient "github.com/cometbft/cometbft/rpc/client"
 	"github.com/cosmos/cosmos-sdk/client"
@@ -18,37 +16,55 @@ import (
 	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
 	"github.com/gorilla/mux"
 	"github.com/spf13/viper"
+	"github.com/terra-money/mantlemint/mantlemint"
 )
 
+// Options customize the api server on top of app.toml
+type Options struct {
+	// Address overrides api.address of app.toml if set
+	Address string
+	// CacheSize and ArchivalCacheSize are capacities of response caches, in number of responses
+	CacheSize         int
+	ArchivalCacheSize int
+	// Home is the home directory of the client context
+	Home string
+}
+
 func StartRPC(
-	app *terra.TerraApp,
+	app types.Application,
+	factory mantlemint.AppFactory,
 	rpcclient rpcclient.Client,
 	chainId string,
-	codec params.EncodingConfig,
 	invalidateTrigger chan int64,
+	options Options,
 	registerCustomRoutes func(router *mux.Router),
 	getIsSynced func() bool,
-) error {
+	getIsHalted func() bool,
+) (*api.Server, error) {
 	vp := viper.GetViper()
 	cfg, _ := config.GetConfig(vp)
+	if options.Address != "" {
+		cfg.API.Address = options.Address
+	}
 
-	// create terra client; register all codecs
+	// create app client; register all codecs
+	codec := factory.EncodingConfig()
 	context := client.
 		Context{}.
 		WithClient(rpcclient).
-		WithCodec(codec.Marshaler).
+		WithCodec(codec.Codec).
 		WithInterfaceRegistry(codec.InterfaceRegistry).
 		WithTxConfig(codec.TxConfig).
 		WithAccountRetriever(authtypes.AccountRetriever{}).
 		WithLegacyAmino(codec.Amino).
-		WithHomeDir(terra.DefaultNodeHome).
+		WithHomeDir(options.Home).
 		WithChainID(chainId)
 
 	// create backends for response cache
 	// - cache: used for latest states without `height` parameter
 	// - archivalCache: used for historical states with `height` parameter; never flushed
-	cache := NewCacheBackend(16384, "latest")
-	archivalCache := NewCacheBackend(16384, "archival")
+	cache := NewCacheBackend(options.CacheSize, "latest")
+	archivalCache := NewCacheBackend(options.ArchivalCacheSize, "archival")
 
 	// register cache invalidator
 	go func() {
@@ -73,7 +89,11 @@ func StartRPC(
 	// custom healthcheck endpoint
 	apiSrv.Router.Handle("/health", http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
 		isSynced := getIsSynced()
-		if isSynced {
+		if getIsHalted() {
+			// frozen on purpose at halt height/time; still serving queries
+			writer.WriteHeader(http.StatusOK)
+			writer.Write([]byte("HALTED"))
+		} else if isSynced {
 			writer.WriteHeader(http.StatusOK)
 			writer.Write([]byte("OK"))
 		} else {
@@ -83,8 +103,7 @@ func StartRPC(
 	})).Methods("GET")
 
 	// register all default GET routers...
-	app.RegisterAPIRoutes(apiSrv, cfg.API)
-	app.RegisterTendermintService(context)
+	factory.RegisterAPIRoutes(app, apiSrv, cfg.API, context)
 	errCh := make(chan error)
 
 	// caching middleware
@@ -118,9 +137,9 @@ func StartRPC(
 
 	select {
 	case err := <-errCh:
-		return err
+		return nil, err
 	case <-time.After(types.ServerStartTime): // assume server started successfully
 	}
 
-	return nil
+	return apiSrv, nil
 }
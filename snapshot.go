@@ -0,0 +1,224 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	dbm "github.com/cometbft/cometbft-db"
+	"github.com/spf13/cobra"
+	"github.com/terra-money/mantlemint/config"
+	"github.com/terra-money/mantlemint/db/heleveldb"
+	"github.com/terra-money/mantlemint/db/hld"
+	"github.com/terra-money/mantlemint/mantlemint"
+	"github.com/terra-money/mantlemint/snapshot"
+)
+
+// snapshot sections
+const (
+	snapshotSectionMantlemint = "mantlemint"
+	snapshotSectionIndexer    = "indexer"
+	// $HOME/data, where the wasm VM keeps contract code
+	snapshotSectionData = "data"
+)
+
+// newSnapshotCmd handles `mantlemint snapshot create|restore`.
+// A snapshot is a copy of mantlemint db (app state, tendermint state and blocks),
+// indexer db and $HOME/data at the height mantlemint db is at,
+// to bootstrap another instance without replaying from genesis.
+func newSnapshotCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Create or restore a snapshot of mantlemint db, indexer db and wasm data",
+	}
+
+	createCmd := &cobra.Command{
+		Use:   "create",
+		Short: "Write a snapshot at the current height; mantlemint must not be running",
+		Long: "Write a snapshot at the current height; mantlemint must not be running.\n" +
+			"To snapshot a specific height, sync up to it with --halt-height first.",
+		Args: cobra.NoArgs,
+	}
+	output := createCmd.Flags().String("output", "", "directory to write the snapshot to; must be empty or not exist")
+	chunkSize := createCmd.Flags().Int64("chunk-size", snapshot.DefaultChunkSize>>20, "uncompressed size of a chunk, in MiB")
+	_ = createCmd.MarkFlagRequired("output")
+	createCmd.RunE = func(cmd *cobra.Command, _ []string) error {
+		mantlemintConfig, err := config.NewConfig(cmd.Flags())
+		if err != nil {
+			return err
+		}
+		return createSnapshot(mantlemintConfig, *output, *chunkSize<<20)
+	}
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Restore a snapshot into an empty mantlemint home",
+		Args:  cobra.NoArgs,
+	}
+	input := restoreCmd.Flags().String("input", "", "snapshot directory")
+	_ = restoreCmd.MarkFlagRequired("input")
+	restoreCmd.RunE = func(cmd *cobra.Command, _ []string) error {
+		mantlemintConfig, err := config.NewConfig(cmd.Flags())
+		if err != nil {
+			return err
+		}
+		return restoreSnapshot(mantlemintConfig, *input)
+	}
+
+	cmd.AddCommand(createCmd, restoreCmd)
+	return cmd
+}
+
+func createSnapshot(mantlemintConfig config.Config, output string, chunkSize int64) error {
+	ldb := openDB(mantlemintConfig)
+	defer ldb.Close()
+
+	height, heightErr := mantlemint.LastBlockHeight(hld.ApplyHeightLimitedDB(ldb, &hld.HeightLimitedDBConfig{}))
+	if heightErr != nil {
+		return heightErr
+	}
+	if height == 0 {
+		return fmt.Errorf("[snapshot] mantlemint db is empty")
+	}
+	schedule, scheduleErr := mantlemint.NewAppSchedule(mantlemintConfig.App, mantlemintConfig.Upgrades)
+	if scheduleErr != nil {
+		return scheduleErr
+	}
+
+	indexerDB, indexerDBErr := openSessionDB(mantlemintConfig, mantlemintConfig.IndexerDB)
+	if indexerDBErr != nil {
+		return indexerDBErr
+	}
+	defer indexerDB.Close()
+
+	writer, writerErr := snapshot.NewWriter(output, mantlemintConfig.ChainID, schedule.AppAt(height+1), height, chunkSize)
+	if writerErr != nil {
+		return writerErr
+	}
+
+	// history moved to cold partitions goes back into mantlemint db
+	coldPartitions, coldErr := ldb.ColdPartitions()
+	if coldErr != nil {
+		return coldErr
+	}
+
+	log.Printf("[snapshot] writing snapshot at height %d to %s", height, output)
+	if err := writer.WriteDBs(snapshotSectionMantlemint, append([]dbm.DB{ldb.Session()}, coldPartitions...)...); err != nil {
+		return err
+	}
+	log.Printf("[snapshot] mantlemint db done")
+	if err := writer.WriteDB(snapshotSectionIndexer, indexerDB); err != nil {
+		return err
+	}
+	log.Printf("[snapshot] indexer db done")
+	if err := writer.WriteDir(snapshotSectionData, filepath.Join(mantlemintConfig.Home, "data")); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	for _, section := range writer.Manifest().Sections {
+		log.Printf("[snapshot] %s: %d entries in %d chunks", section.Name, section.Entries, len(section.Chunks))
+	}
+	log.Printf("[snapshot] done")
+	return nil
+}
+
+func restoreSnapshot(mantlemintConfig config.Config, input string) error {
+	if mantlemintConfig.DBBackend == config.DBBackendMemDB {
+		return fmt.Errorf("[snapshot] nothing to restore into with storage.backend %s", config.DBBackendMemDB)
+	}
+	manifest, manifestErr := snapshot.ReadManifest(input)
+	if manifestErr != nil {
+		return manifestErr
+	}
+	if manifest.ChainID != mantlemintConfig.ChainID {
+		return fmt.Errorf("[snapshot] snapshot is of chain %s, expected %s", manifest.ChainID, mantlemintConfig.ChainID)
+	}
+
+	dbs := map[string]string{
+		snapshotSectionMantlemint: mantlemintConfig.MantlemintDB,
+		snapshotSectionIndexer:    mantlemintConfig.IndexerDB,
+	}
+	dataDir := filepath.Join(mantlemintConfig.Home, "data")
+
+	// never mix a snapshot into existing state
+	for _, name := range dbs {
+		if _, err := os.Stat(filepath.Join(mantlemintConfig.Home, name+".db")); err == nil {
+			return fmt.Errorf("[snapshot] %s.db already exists in %s", name, mantlemintConfig.Home)
+		}
+	}
+	if entries, err := os.ReadDir(dataDir); err == nil && len(entries) > 0 {
+		return fmt.Errorf("[snapshot] %s is not empty", dataDir)
+	}
+
+	log.Printf("[snapshot] restoring snapshot at height %d, created %s", manifest.Height, manifest.CreatedAt)
+
+	// restore next to the final locations, moving them in place only once every chunk checks out
+	var scratch []string
+	defer func() {
+		for _, path := range scratch {
+			_ = os.RemoveAll(path)
+		}
+	}()
+
+	for _, section := range []string{snapshotSectionMantlemint, snapshotSectionIndexer} {
+		scratchName := dbs[section] + ".restore"
+		scratchPath := filepath.Join(mantlemintConfig.Home, scratchName+".db")
+		scratch = append(scratch, scratchPath)
+
+		// left over by an interrupted restore
+		if err := os.RemoveAll(scratchPath); err != nil {
+			return err
+		}
+
+		db, dbErr := openSessionDB(mantlemintConfig, scratchName)
+		if dbErr != nil {
+			return dbErr
+		}
+		restoreErr := snapshot.RestoreDB(input, manifest, section, db)
+		if restoreErr == nil && section == snapshotSectionMantlemint {
+			// the snapshot holds history of cold partitions, if any, in mantlemint db itself
+			restoreErr = heleveldb.ResetTiering(db)
+		}
+		closeErr := db.Close()
+		if restoreErr != nil {
+			return fmt.Errorf("[snapshot] %s: %w", section, restoreErr)
+		}
+		if closeErr != nil {
+			return closeErr
+		}
+		log.Printf("[snapshot] %s db restored", section)
+	}
+
+	dataScratch := dataDir + ".restore"
+	scratch = append(scratch, dataScratch)
+	if err := os.RemoveAll(dataScratch); err != nil {
+		return err
+	}
+	if err := snapshot.RestoreDir(input, manifest, snapshotSectionData, dataScratch); err != nil {
+		return fmt.Errorf("[snapshot] %s: %w", snapshotSectionData, err)
+	}
+
+	for _, section := range []string{snapshotSectionMantlemint, snapshotSectionIndexer} {
+		from := filepath.Join(mantlemintConfig.Home, dbs[section]+".restore.db")
+		if err := os.Rename(from, filepath.Join(mantlemintConfig.Home, dbs[section]+".db")); err != nil {
+			return err
+		}
+	}
+	if err := os.Remove(dataDir); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.MkdirAll(dataScratch, 0o755); err != nil {
+		return err
+	}
+	if err := os.Rename(dataScratch, dataDir); err != nil {
+		return err
+	}
+
+	log.Printf("[snapshot] done; mantlemint will resume from height %d", manifest.Height+1)
+	return nil
+}
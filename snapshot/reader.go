@@ -0,0 +1,161 @@
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	dbm "github.com/cometbft/cometbft-db"
+	"github.com/golang/snappy"
+)
+
+// restoreBatchSize is how many entries go into a single db batch on restore
+const restoreBatchSize = 10000
+
+// ReadSection calls fn with every entry of section name, in order.
+// Each chunk is verified against its checksum once fully read, so fn may have seen entries
+// of a chunk that turns out to be corrupted; restore into a scratch location.
+func ReadSection(dir string, manifest Manifest, name string, fn func(key, value []byte) error) error {
+	section, ok := manifest.Section(name)
+	if !ok {
+		return fmt.Errorf("snapshot has no %s section", name)
+	}
+
+	var entries int64
+	for _, chunk := range section.Chunks {
+		read, err := readChunk(filepath.Join(dir, chunk.File), chunk, fn)
+		if err != nil {
+			return fmt.Errorf("%s: %w", chunk.File, err)
+		}
+		entries += read
+	}
+	if entries != section.Entries {
+		return fmt.Errorf("%w: %s has %d entries, expected %d", ErrSnapshotCorrupted, name, entries, section.Entries)
+	}
+	return nil
+}
+
+func readChunk(chunkPath string, chunk Chunk, fn func(key, value []byte) error) (int64, error) {
+	file, err := os.Open(chunkPath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	r := io.TeeReader(file, hash)
+
+	header := make([]byte, len(chunkMagic)+1)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrSnapshotCorrupted, err)
+	}
+	if !bytes.Equal(header[:len(chunkMagic)], chunkMagic) {
+		return 0, fmt.Errorf("%w: bad magic", ErrSnapshotCorrupted)
+	}
+	if header[len(chunkMagic)] != Version {
+		return 0, fmt.Errorf("unsupported chunk version %d", header[len(chunkMagic)])
+	}
+
+	stream := bufio.NewReader(snappy.NewReader(r))
+	var entries int64
+	for {
+		key, err := readField(stream)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return entries, err
+		}
+		value, err := readField(stream)
+		if err != nil {
+			return entries, err
+		}
+		if err := fn(key, value); err != nil {
+			return entries, err
+		}
+		entries++
+	}
+
+	// anything trailing the snappy stream still counts towards the checksum
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return entries, err
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != chunk.SHA256 {
+		return entries, fmt.Errorf("%w: checksum %s, expected %s", ErrSnapshotCorrupted, sum, chunk.SHA256)
+	}
+	if entries != chunk.Entries {
+		return entries, fmt.Errorf("%w: %d entries, expected %d", ErrSnapshotCorrupted, entries, chunk.Entries)
+	}
+	return entries, nil
+}
+
+// readField reads a length prefixed field; io.EOF only if the stream ends right before it
+func readField(r *bufio.Reader) ([]byte, error) {
+	length, err := binary.ReadUvarint(r)
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSnapshotCorrupted, err)
+	}
+	if length > maxEntrySize {
+		return nil, fmt.Errorf("%w: entry of %d bytes", ErrSnapshotCorrupted, length)
+	}
+
+	field := make([]byte, length)
+	if _, err := io.ReadFull(r, field); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSnapshotCorrupted, err)
+	}
+	return field, nil
+}
+
+// RestoreDB writes every entry of section name into db
+func RestoreDB(dir string, manifest Manifest, name string, db dbm.DB) error {
+	batch := db.NewBatch()
+	defer func() { batch.Close() }()
+
+	pending := 0
+	readErr := ReadSection(dir, manifest, name, func(key, value []byte) error {
+		if err := batch.Set(key, value); err != nil {
+			return err
+		}
+		if pending++; pending < restoreBatchSize {
+			return nil
+		}
+
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		batch.Close()
+		batch = db.NewBatch()
+		pending = 0
+		return nil
+	})
+	if readErr != nil {
+		return readErr
+	}
+	return batch.WriteSync()
+}
+
+// RestoreDir writes every file of section name under root
+func RestoreDir(dir string, manifest Manifest, name string, root string) error {
+	return ReadSection(dir, manifest, name, func(key, value []byte) error {
+		rel := path.Clean(string(key))
+		if path.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, "../") {
+			return fmt.Errorf("%w: file %q outside of %s", ErrSnapshotCorrupted, key, name)
+		}
+
+		target := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		return os.WriteFile(target, value, 0o644)
+	})
+}
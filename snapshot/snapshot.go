@@ -0,0 +1,92 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Snapshot is a directory of a manifest and chunk files, each chunk holding entries of a single section:
+//
+//	chunk: magic "MMSC" | version (1 byte) | snappy framed stream of entries
+//	entry: key length (uvarint) | key | value length (uvarint) | value
+//
+// A section is either a db, with its raw keys and values, or a directory, with files keyed by
+// slash separated relative paths. Chunks are checksummed in the manifest, which is written last;
+// a directory without one is an incomplete snapshot.
+const (
+	Version byte = 1
+
+	ManifestFileName = "manifest.json"
+
+	// DefaultChunkSize is the uncompressed size a chunk is closed at
+	DefaultChunkSize = 64 << 20
+
+	// guards against allocating absurd buffers for corrupted length fields
+	maxEntrySize = 1 << 30
+)
+
+var (
+	chunkMagic = []byte("MMSC")
+
+	ErrSnapshotCorrupted = errors.New("snapshot is corrupted")
+)
+
+// Manifest describes a snapshot
+type Manifest struct {
+	Version   byte      `json:"version"`
+	ChainID   string    `json:"chain_id"`
+	App       string    `json:"app"`
+	Height    int64     `json:"height"`
+	CreatedAt time.Time `json:"created_at"`
+	Sections  []Section `json:"sections"`
+}
+
+// Section returns the section with name, if present
+func (m Manifest) Section(name string) (Section, bool) {
+	for _, section := range m.Sections {
+		if section.Name == name {
+			return section, true
+		}
+	}
+	return Section{}, false
+}
+
+type Section struct {
+	Name    string  `json:"name"`
+	Entries int64   `json:"entries"`
+	Chunks  []Chunk `json:"chunks"`
+}
+
+type Chunk struct {
+	File    string `json:"file"`
+	Size    int64  `json:"size"`
+	Entries int64  `json:"entries"`
+	SHA256  string `json:"sha256"`
+}
+
+func chunkFileName(section string, index int) string {
+	return fmt.Sprintf("%s.%06d.chunk", section, index)
+}
+
+// ReadManifest reads the manifest of the snapshot in dir
+func ReadManifest(dir string) (Manifest, error) {
+	manifestJSON, err := os.ReadFile(filepath.Join(dir, ManifestFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return Manifest{}, fmt.Errorf("%s has no %s; not a snapshot, or an incomplete one", dir, ManifestFileName)
+	} else if err != nil {
+		return Manifest{}, err
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(manifestJSON, &manifest); err != nil {
+		return Manifest{}, fmt.Errorf("%w: %v", ErrSnapshotCorrupted, err)
+	}
+	if manifest.Version != Version {
+		return Manifest{}, fmt.Errorf("unsupported snapshot version %d", manifest.Version)
+	}
+	return manifest, nil
+}
@@ -0,0 +1,79 @@
+package snapshot
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	dbm "github.com/cometbft/cometbft-db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "snapshot")
+
+	db := dbm.NewMemDB()
+	for i := 0; i < 1000; i++ {
+		assert.Nil(t, db.Set([]byte(fmt.Sprintf("key-%04d", i)), []byte(fmt.Sprintf("value-%d", i))))
+	}
+	dataDir := t.TempDir()
+	assert.Nil(t, os.MkdirAll(filepath.Join(dataDir, "wasm", "state"), 0o755))
+	assert.Nil(t, os.WriteFile(filepath.Join(dataDir, "wasm", "state", "code"), []byte("wasm"), 0o644))
+
+	// tiny chunks to exercise chunking
+	writer, err := NewWriter(dir, "columbus-5", "terra-classic", 42, 1024)
+	assert.Nil(t, err)
+	assert.Nil(t, writer.WriteDB("mantlemint", db))
+	assert.Nil(t, writer.WriteDir("data", dataDir))
+	assert.Nil(t, writer.WriteDir("missing", filepath.Join(dataDir, "missing")))
+	assert.Nil(t, writer.Close())
+
+	manifest, err := ReadManifest(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(42), manifest.Height)
+	section, ok := manifest.Section("mantlemint")
+	assert.True(t, ok)
+	assert.Equal(t, int64(1000), section.Entries)
+	assert.Greater(t, len(section.Chunks), 1)
+
+	restored := dbm.NewMemDB()
+	assert.Nil(t, RestoreDB(dir, manifest, "mantlemint", restored))
+	value, err := restored.Get([]byte("key-0999"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("value-999"), value)
+
+	restoredDataDir := t.TempDir()
+	assert.Nil(t, RestoreDir(dir, manifest, "data", restoredDataDir))
+	code, err := os.ReadFile(filepath.Join(restoredDataDir, "wasm", "state", "code"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("wasm"), code)
+
+	// a snapshot can't be written over another
+	_, err = NewWriter(dir, "columbus-5", "terra-classic", 43, 0)
+	assert.NotNil(t, err)
+}
+
+func TestSnapshotCorrupted(t *testing.T) {
+	dir := t.TempDir()
+
+	db := dbm.NewMemDB()
+	for i := 0; i < 100; i++ {
+		assert.Nil(t, db.Set([]byte(fmt.Sprintf("key-%04d", i)), []byte("value")))
+	}
+	writer, err := NewWriter(dir, "columbus-5", "terra-classic", 1, 0)
+	assert.Nil(t, err)
+	assert.Nil(t, writer.WriteDB("mantlemint", db))
+	assert.Nil(t, writer.Close())
+
+	manifest, err := ReadManifest(dir)
+	assert.Nil(t, err)
+	chunkPath := filepath.Join(dir, manifest.Sections[0].Chunks[0].File)
+	chunk, err := os.ReadFile(chunkPath)
+	assert.Nil(t, err)
+	chunk[len(chunk)-1] ^= 0xff
+	assert.Nil(t, os.WriteFile(chunkPath, chunk, 0o644))
+
+	err = RestoreDB(dir, manifest, "mantlemint", dbm.NewMemDB())
+	assert.ErrorIs(t, err, ErrSnapshotCorrupted)
+}
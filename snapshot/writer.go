@@ -0,0 +1,235 @@
+package snapshot
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	dbm "github.com/cometbft/cometbft-db"
+	"github.com/golang/snappy"
+)
+
+type Writer struct {
+	dir       string
+	chunkSize int64
+	manifest  Manifest
+}
+
+// NewWriter starts a snapshot at height in dir, which must be empty or not exist yet
+func NewWriter(dir string, chainID string, app string, height int64, chunkSize int64) (*Writer, error) {
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("%s is not empty", dir)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	return &Writer{
+		dir:       dir,
+		chunkSize: chunkSize,
+		manifest: Manifest{
+			Version:   Version,
+			ChainID:   chainID,
+			App:       app,
+			Height:    height,
+			CreatedAt: time.Now().UTC(),
+		},
+	}, nil
+}
+
+// WriteDB adds every key of db as section name
+func (w *Writer) WriteDB(name string, db dbm.DB) error {
+	return w.WriteDBs(name, db)
+}
+
+// WriteDBs adds every key of dbs, one db after another, as section name
+func (w *Writer) WriteDBs(name string, dbs ...dbm.DB) error {
+	section := w.newSection(name)
+	for _, db := range dbs {
+		if err := section.addDB(db); err != nil {
+			return err
+		}
+	}
+	return section.close()
+}
+
+// WriteDir adds every file under root as section name; a missing root makes an empty section
+func (w *Writer) WriteDir(name string, root string) error {
+	section := w.newSection(name)
+	walkErr := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && path == root {
+			return filepath.SkipDir
+		} else if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return section.add([]byte(filepath.ToSlash(rel)), content)
+	})
+	if walkErr != nil {
+		return walkErr
+	}
+	return section.close()
+}
+
+// Close writes the manifest, completing the snapshot
+func (w *Writer) Close() error {
+	manifestJSON, err := json.MarshalIndent(w.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// never leave a partial manifest behind
+	tmpPath := filepath.Join(w.dir, ManifestFileName+".tmp")
+	if err := os.WriteFile(tmpPath, manifestJSON, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filepath.Join(w.dir, ManifestFileName))
+}
+
+func (w *Writer) Manifest() Manifest {
+	return w.manifest
+}
+
+// sectionWriter splits entries of a section into chunks of about chunkSize
+type sectionWriter struct {
+	w       *Writer
+	section Section
+
+	file    *os.File
+	hash    hash.Hash
+	size    *countingWriter
+	stream  *snappy.Writer
+	written int64
+	entries int64
+}
+
+func (w *Writer) newSection(name string) *sectionWriter {
+	return &sectionWriter{
+		w:       w,
+		section: Section{Name: name},
+	}
+}
+
+func (s *sectionWriter) add(key, value []byte) error {
+	if s.file == nil {
+		if err := s.openChunk(); err != nil {
+			return err
+		}
+	}
+
+	entry := make([]byte, 0, 2*binary.MaxVarintLen64+len(key)+len(value))
+	entry = binary.AppendUvarint(entry, uint64(len(key)))
+	entry = append(entry, key...)
+	entry = binary.AppendUvarint(entry, uint64(len(value)))
+	entry = append(entry, value...)
+	if _, err := s.stream.Write(entry); err != nil {
+		return err
+	}
+
+	s.written += int64(len(entry))
+	s.entries++
+	s.section.Entries++
+	if s.written >= s.w.chunkSize {
+		return s.closeChunk()
+	}
+	return nil
+}
+
+func (s *sectionWriter) addDB(db dbm.DB) error {
+	it, err := db.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	for ; it.Valid(); it.Next() {
+		if err := s.add(it.Key(), it.Value()); err != nil {
+			return err
+		}
+	}
+	return it.Error()
+}
+
+func (s *sectionWriter) openChunk() error {
+	file, err := os.Create(filepath.Join(s.w.dir, chunkFileName(s.section.Name, len(s.section.Chunks))))
+	if err != nil {
+		return err
+	}
+
+	s.file = file
+	s.hash = sha256.New()
+	s.size = &countingWriter{}
+	out := io.MultiWriter(file, s.hash, s.size)
+
+	header := append(append([]byte{}, chunkMagic...), Version)
+	if _, err := out.Write(header); err != nil {
+		return err
+	}
+	s.stream = snappy.NewBufferedWriter(out)
+	s.written = 0
+	s.entries = 0
+	return nil
+}
+
+func (s *sectionWriter) closeChunk() error {
+	if err := s.stream.Close(); err != nil {
+		return err
+	}
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+
+	s.section.Chunks = append(s.section.Chunks, Chunk{
+		File:    filepath.Base(s.file.Name()),
+		Size:    s.size.n,
+		Entries: s.entries,
+		SHA256:  hex.EncodeToString(s.hash.Sum(nil)),
+	})
+	s.file = nil
+	return nil
+}
+
+func (s *sectionWriter) close() error {
+	if s.file != nil {
+		if err := s.closeChunk(); err != nil {
+			return err
+		}
+	}
+	s.w.manifest.Sections = append(s.w.manifest.Sections, s.section)
+	return nil
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,45 @@
+package rootmulti
+
+import (
+	"sort"
+
+	dbm "github.com/cometbft/cometbft-db"
+	"github.com/cosmos/cosmos-sdk/store/types"
+)
+
+// StorePrefix is the prefix a store named name keeps its keys under,
+// in the upstream rootmulti store's db as well as in mantlemint's
+func StorePrefix(name string) []byte {
+	return []byte("s/k:" + name + "/")
+}
+
+// GetCommitInfo reads commit info of version from db
+func GetCommitInfo(db dbm.DB, version int64) (*types.CommitInfo, error) {
+	return getCommitInfo(db, version)
+}
+
+// FlushImportedVersion writes metadata of version for stores whose keys were written
+// into db directly, committing them as db stores would have; the app loads version as latest.
+func FlushImportedVersion(db dbm.DB, version int64, storeNames []string) error {
+	storeInfos := make([]types.StoreInfo, 0, len(storeNames))
+	for _, name := range storeNames {
+		storeInfos = append(storeInfos, types.StoreInfo{
+			Name: name,
+			CommitId: types.CommitID{
+				Version: -1,
+				Hash:    commithash,
+			},
+		})
+	}
+	sort.SliceStable(storeInfos, func(i, j int) bool { return storeInfos[i].Name < storeInfos[j].Name })
+
+	batch := db.NewBatch()
+	defer batch.Close()
+
+	flushCommitInfo(batch, version, &types.CommitInfo{
+		Version:    version,
+		StoreInfos: storeInfos,
+	})
+	flushLatestVersion(batch, version)
+	return batch.WriteSync()
+}
@@ -484,6 +484,9 @@ func (rs *Store) CacheMultiStore() types.CacheMultiStore {
 // iterating at past heights.
 func (rs *Store) CacheMultiStoreWithVersion(version int64) (types.CacheMultiStore, error) {
 	hldb := rs.hldb.BranchHeightLimitedDB(version)
+	if err := hldb.CheckReadHeight(); err != nil {
+		return nil, err
+	}
 
 	cachedStores := make(map[types.StoreKey]types.CacheWrapper)
 	var commitInfo *types.CommitInfo
@@ -920,7 +923,7 @@ func (rs *Store) loadCommitStoreFromParams(key types.StoreKey, id types.CommitID
 		prefix = []byte("s/_/")
 		db = dbm.NewPrefixDB(params.db, prefix)
 	} else {
-		prefix = []byte("s/k:" + params.key.Name() + "/")
+		prefix = StorePrefix(params.key.Name())
 		db = dbm.NewPrefixDB(rs.db, prefix)
 	}
 
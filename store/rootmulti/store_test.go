@@ -0,0 +1,74 @@
+package rootmulti
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/cosmos/cosmos-sdk/store/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/terra-money/mantlemint/db/heleveldb"
+	"github.com/terra-money/mantlemint/db/hememdb"
+	"github.com/terra-money/mantlemint/db/hld"
+	"github.com/terra-money/mantlemint/db/safe_batch"
+)
+
+func TestCacheMultiStoreWithVersion(t *testing.T) {
+	driver := hememdb.NewMemDBDriver(&heleveldb.DriverConfig{Mode: heleveldb.DriverModeKeySuffixDesc})
+	hldb := hld.ApplyHeightLimitedDB(driver, &hld.HeightLimitedDBConfig{})
+	batched := safe_batch.NewSafeBatchDB(hldb)
+	batchedOrigin := batched.(safe_batch.SafeBatchDBCloser)
+
+	key := types.NewKVStoreKey("bank")
+	store := NewStore(batched, log.NewNopLogger(), hldb)
+	store.MountStoreWithDB(key, types.StoreTypeDB, nil)
+	assert.Nil(t, store.LoadLatestVersion())
+
+	// as mantlemint commits a block
+	commit := func(height int64, kvs map[string]string) {
+		hldb.SetWriteHeight(height)
+		batchedOrigin.Open()
+		for k, v := range kvs {
+			if v == "" {
+				store.GetKVStore(key).Delete([]byte(k))
+			} else {
+				store.GetKVStore(key).Set([]byte(k), []byte(v))
+			}
+		}
+		store.Commit()
+		_, err := batchedOrigin.Flush()
+		assert.Nil(t, err)
+		hldb.ClearWriteHeight()
+	}
+	commit(1, map[string]string{"a": "1", "b": "1"})
+	commit(2, map[string]string{"a": "2", "b": ""})
+
+	assert.Equal(t, int64(2), store.LastCommitID().Version)
+	latest := store.GetKVStore(key)
+	assert.Equal(t, []byte("2"), latest.Get([]byte("a")))
+	assert.Nil(t, latest.Get([]byte("b")))
+
+	cms, err := store.CacheMultiStoreWithVersion(1)
+	assert.Nil(t, err)
+	historical := cms.GetKVStore(key)
+	assert.Equal(t, []byte("1"), historical.Get([]byte("a")))
+	assert.Equal(t, []byte("1"), historical.Get([]byte("b")))
+
+	it := historical.Iterator(nil, nil)
+	defer it.Close()
+	var keys []string
+	for ; it.Valid(); it.Next() {
+		keys = append(keys, string(it.Key()))
+	}
+	assert.Equal(t, []string{"a", "b"}, keys)
+
+	// height 1 is gone once pruned
+	commit(3, map[string]string{"a": "3"})
+	_, err = driver.Prune(context.Background(), heleveldb.PruningPolicy{KeepRecent: 2}, 3)
+	assert.Nil(t, err)
+	_, err = store.CacheMultiStoreWithVersion(1)
+	assert.ErrorIs(t, err, heleveldb.ErrHeightPruned)
+	cms, err = store.CacheMultiStoreWithVersion(2)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("2"), cms.GetKVStore(key).Get([]byte("a")))
+}
@@ -1,168 +1,171 @@
 package main
 
 import (
-	"crypto/sha1"
-	"encoding/hex"
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"runtime/debug"
+	"syscall"
+	"time"
 
-	"github.com/CosmWasm/wasmd/x/wasm"
-	wasmtypes "github.com/CosmWasm/wasmd/x/wasm/types"
-	terra "github.com/classic-terra/core/v3/app"
-	core "github.com/classic-terra/core/v3/types"
-	tmlog "github.com/cometbft/cometbft/libs/log"
-	"github.com/cometbft/cometbft/proxy"
+	"github.com/cometbft/cometbft/state"
 	tendermint "github.com/cometbft/cometbft/types"
-	"github.com/cosmos/cosmos-sdk/baseapp"
-	simtestutil "github.com/cosmos/cosmos-sdk/testutil/sims"
-	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/gorilla/mux"
 	blockFeeder "github.com/terra-money/mantlemint/block_feed"
 	"github.com/terra-money/mantlemint/config"
 	"github.com/terra-money/mantlemint/db/heleveldb"
-	"github.com/terra-money/mantlemint/db/hld"
-	"github.com/terra-money/mantlemint/db/safe_batch"
-	"github.com/terra-money/mantlemint/indexer"
-	"github.com/terra-money/mantlemint/indexer/block"
-	"github.com/terra-money/mantlemint/indexer/tx"
 	"github.com/terra-money/mantlemint/mantlemint"
 	"github.com/terra-money/mantlemint/rpc"
-	"github.com/terra-money/mantlemint/store/rootmulti"
+	"github.com/terra-money/mantlemint/verifier"
 
 	dbm "github.com/cometbft/cometbft-db"
 )
 
-// initialize mantlemint for v0.34.x
-func main() {
-	mantlemintConfig := config.NewConfig()
+// start runs mantlemint; it syncs blocks from the configured feed and serves queries
+// until SIGINT/SIGTERM, or until syncing halts
+func start(mantlemintConfig config.Config) {
 	mantlemintConfig.Print()
 
-	sdkConfig := sdk.GetConfig()
-	sdkConfig.SetCoinType(core.CoinType)
-	sdkConfig.SetBech32PrefixForAccount(core.Bech32PrefixAccAddr, core.Bech32PrefixAccPub)
-	sdkConfig.SetBech32PrefixForValidator(core.Bech32PrefixValAddr, core.Bech32PrefixValPub)
-	sdkConfig.SetBech32PrefixForConsensusNode(core.Bech32PrefixConsAddr, core.Bech32PrefixConsPub)
-	sdkConfig.SetAddressVerifier(wasmtypes.VerifyAddressLen())
-	sdkConfig.Seal()
-
-	ldb, ldbErr := heleveldb.NewLevelDBDriver(&heleveldb.DriverConfig{
-		Name: mantlemintConfig.MantlemintDB,
-		Dir:  mantlemintConfig.Home,
-		Mode: heleveldb.DriverModeKeySuffixDesc,
-	})
-	if ldbErr != nil {
-		panic(ldbErr)
-	}
-
-	hldb := hld.ApplyHeightLimitedDB(
-		ldb,
-		&hld.HeightLimitedDBConfig{
-			Debug: true,
-		},
-	)
-
-	batched := safe_batch.NewSafeBatchDB(hldb)
-	batchedOrigin := batched.(safe_batch.SafeBatchDBCloser)
-	logger := tmlog.NewTMLogger(os.Stdout)
-	codec := terra.MakeEncodingConfig()
-
-	// customize CMS to limit kv store's read height on query
-	cms := rootmulti.NewStore(batched, logger, hldb)
-	//vpr := viper.GetViper()
-	appOptions := make(simtestutil.AppOptionsMap, 0)
-
-	var wasmOpts []wasm.Option
-	app := terra.NewTerraApp(
-		logger,
-		batched,
-		nil,
-		true, // need this so KVStores are set
-		make(map[int64]bool),
-		mantlemintConfig.Home,
-		codec,
-		appOptions,
-		wasmOpts,
-		fauxMerkleModeOpt,
-		func(ba *baseapp.BaseApp) {
-			ba.SetCMS(cms)
-		},
-		baseapp.SetChainID(mantlemintConfig.ChainID),
-	)
+	resultsHashPolicy := mantlemint.ResultsHashPolicy(mantlemintConfig.ResultsHashPolicy)
 
-	// create app...
-	appCreator := mantlemint.NewConcurrentQueryClientCreator(app)
-	appMetrics := proxy.NopMetrics()
-	appConns := proxy.NewAppConns(appCreator, appMetrics)
-	appConns.SetLogger(logger)
-	if startErr := appConns.OnStart(); startErr != nil {
-		panic(startErr)
+	haltCondition := mantlemint.HaltCondition{Height: mantlemintConfig.HaltHeight}
+	if mantlemintConfig.HaltTime > 0 {
+		haltCondition.Time = time.Unix(mantlemintConfig.HaltTime, 0)
 	}
+	halter := mantlemint.NewHalter(haltCondition)
+
+	// stop accepting blocks on SIGINT/SIGTERM; the block being injected
+	// at the time of the signal is always finished and flushed first
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// optionally cross-check execution results against upstream;
+	// the verifier is created along with the block feed, before any block is injected
+	var resultsVerifier *verifier.BlockResultsVerifier
+
+	// blocks injected earlier, and commits of blocks being injected, are fetched from here;
+	// it is set along with the block feed
+	var blockFetcher blockFeeder.BlockFetcher
+
+	n := newNode(mantlemintConfig, func(getState func() state.State) (mantlemint.MantlemintCallbackBefore, mantlemint.MantlemintCallbackAfter) {
+		var commitVerifier mantlemint.MantlemintCallbackBefore
+		if mantlemintConfig.VerifyCommits {
+			commitVerifier = mantlemint.NewCommitVerifier(getState, mantlemintConfig.ChainID, func(height int64) (*tendermint.Commit, error) {
+				return blockFetcher.FetchCommit(height)
+			})
+		}
 
-	go func() {
-		a := <-appConns.Quit()
-		fmt.Println(a)
-	}()
-
-	executor := mantlemint.NewMantlemintExecutor(batched, appConns.Consensus())
-	mm := mantlemint.NewMantlemint(
-		batched,
-		appConns,
-		executor,
-
-		// run before
-		nil,
-
-		// RunAfter Inject callback
-		nil,
-	)
+		var runAfter mantlemint.MantlemintCallbackAfter
+		if mantlemintConfig.VerifyBlockResults {
+			runAfter = func(block *tendermint.Block, events *mantlemint.EventCollector) error {
+				return resultsVerifier.RunAfter(block, events)
+			}
+		}
 
-	// initialize using provided genesis
-	genesisDoc := getGenesisDoc(mantlemintConfig.GenesisPath)
-	initialHeight := genesisDoc.InitialHeight
+		// run before; verify the incoming block, and results of the last block against its header
+		return mantlemint.ChainCallbackBefore(commitVerifier, mantlemint.NewResultsHashVerifier(getState, resultsHashPolicy)), runAfter
+	})
+	ldb, hldb, batched, batchedOrigin := n.ldb, n.hldb, n.batched, n.batchedOrigin
+	app, appCreator, appConns, mm := n.app, n.appCreator, n.appConns, n.mm
 
-	// set target initial write height to genesis.initialHeight;
-	// this is safe as upon Inject it will be set with block.Height
-	hldb.SetWriteHeight(initialHeight)
-	batchedOrigin.Open()
+	// get blocks over some sort of transport, inject to mantlemint
+	var blockFeed blockFeeder.SyncAwareBlockFeed
+	if mantlemintConfig.ArchivePath != "" {
+		archiveFeed, archiveErr := blockFeeder.NewArchiveSubscription(
+			mm.GetCurrentHeight(),
+			mantlemintConfig.ArchivePath,
+		)
+		if archiveErr != nil {
+			panic(archiveErr)
+		}
+		blockFeed = archiveFeed
 
-	// initialize state machine with genesis
-	if initErr := mm.Init(genesisDoc); initErr != nil {
-		panic(initErr)
+		// replayed blocks are cross-checked against results in the archive rather than an RPC
+		if mantlemintConfig.VerifyBlockResults {
+			resultsVerifier = verifier.NewBlockResultsVerifierFrom(archiveFeed.BlockResults)
+		}
+	} else if mantlemintConfig.BlockStoreDir != "" {
+		blockStoreFeed, blockStoreErr := blockFeeder.NewBlockStoreSubscription(
+			mm.GetCurrentHeight(),
+			mantlemintConfig.BlockStoreDir,
+		)
+		if blockStoreErr != nil {
+			panic(blockStoreErr)
+		}
+		blockFeed = blockStoreFeed
+	} else if mantlemintConfig.Quorum > 0 {
+		quorumFeed, quorumErr := blockFeeder.NewQuorumSubscription(
+			mm.GetCurrentHeight(),
+			mantlemintConfig.RPCEndpoints,
+			mantlemintConfig.Quorum,
+		)
+		if quorumErr != nil {
+			panic(quorumErr)
+		}
+		blockFeed = quorumFeed
+	} else {
+		// blocks fed after a restart must still chain onto the last injected one
+		blockFeed = blockFeeder.NewAggregateBlockFeed(
+			mm.GetCurrentHeight(),
+			mm.GetCurrentState().LastBlockID.Hash,
+			mantlemintConfig.RPCEndpoints,
+			mantlemintConfig.WSEndpoints,
+			mantlemintConfig.RPCSyncWindow,
+			mantlemintConfig.WSPingInterval,
+			mantlemintConfig.StallTimeout,
+		)
 	}
 
-	// flush to db; panic upon error (can't proceed)
-	if rollback, flushErr := batchedOrigin.Flush(); flushErr != nil {
-		debug.PrintStack()
-		panic(flushErr)
-	} else if rollback != nil {
-		rollback.Close()
+	// blocks and commits come from the local block source if there is one, otherwise from every rpc endpoint
+	var isLocal bool
+	blockFetcher, isLocal = blockFeed.(blockFeeder.BlockFetcher)
+	if !isLocal {
+		rpcFetcher, rpcFetcherErr := blockFeeder.NewRpcSubscription(mantlemintConfig.RPCEndpoints, 1)
+		if rpcFetcherErr != nil {
+			panic(rpcFetcherErr)
+		}
+		// fetching retries until an endpoint answers; give up on SIGINT/SIGTERM
+		stopFetcher := context.AfterFunc(ctx, func() { _ = rpcFetcher.Close() })
+		defer stopFetcher()
+		blockFetcher = rpcFetcher
 	}
 
-	// load initial state to mantlemint
-	if loadErr := mm.LoadInitialState(); loadErr != nil {
-		panic(loadErr)
+	if mantlemintConfig.VerifyBlockResults && resultsVerifier == nil {
+		resultsVerifier = verifier.NewBlockResultsVerifier(mantlemintConfig.VerifyRPCEndpoint)
+	}
+	if resultsVerifier != nil {
+		// injection may be waiting for the verifier to catch up; release it on SIGINT/SIGTERM
+		stopVerifier := context.AfterFunc(ctx, func() { _ = resultsVerifier.Close() })
+		defer stopVerifier()
 	}
 
-	// initialization is done; clear write height
-	hldb.ClearWriteHeight()
-
-	// get blocks over some sort of transport, inject to mantlemint
-	blockFeed := blockFeeder.NewAggregateBlockFeed(
+	// delete state of heights no longer kept, in the background
+	prunerInstance := startPruner(
+		ldb,
+		heleveldb.PruningPolicy{
+			KeepRecent: mantlemintConfig.PruningKeepRecent,
+			KeepEvery:  mantlemintConfig.PruningKeepEvery,
+		},
+		mantlemintConfig.PruningInterval,
 		mm.GetCurrentHeight(),
-		mantlemintConfig.RPCEndpoints,
-		mantlemintConfig.WSEndpoints,
 	)
+	// move history to cold partitions, in the background
+	tieringInstance := startTiering(ldb, mantlemintConfig.TieringColdDir != "", mantlemintConfig.TieringInterval, mm.GetCurrentHeight())
 
 	// create indexer service
-	indexerInstance, indexerInstanceErr := indexer.NewIndexer(mantlemintConfig.IndexerDB, mantlemintConfig.Home)
+	indexerInstance, indexerInstanceErr := newIndexer(mantlemintConfig, n.codec)
 	if indexerInstanceErr != nil {
 		panic(indexerInstanceErr)
 	}
 
-	indexerInstance.RegisterIndexerService("tx", tx.IndexTx)
-	indexerInstance.RegisterIndexerService("block", block.IndexBlock)
+	// indexer db is committed separately from mantlemint db;
+	// bring it to the same height before accepting new blocks
+	if reconcileErr := indexerInstance.Reconcile(mm.GetCurrentHeight(), injectedBlockSource(blockFetcher, mm.LoadEventCollector)); reconcileErr != nil {
+		panic(reconcileErr)
+	}
 
 	abcicli, _ := appCreator.NewABCIClient()
 	rpccli := rpc.NewRpcClient(abcicli)
@@ -171,44 +174,122 @@ func main() {
 	cacheInvalidateChan := make(chan int64)
 
 	// start RPC server
-	rpcErr := rpc.StartRPC(
+	apiSrv, rpcErr := rpc.StartRPC(
 		app,
+		n.factory,
 		rpccli,
 		mantlemintConfig.ChainID,
-		codec,
 		cacheInvalidateChan,
+		rpc.Options{
+			Address:           mantlemintConfig.APIAddress,
+			CacheSize:         mantlemintConfig.CacheSize,
+			ArchivalCacheSize: mantlemintConfig.ArchivalCacheSize,
+			Home:              mantlemintConfig.Home,
+		},
 
 		// callback for registering custom routers; primarily for indexers
 		// default: noop,
 		// todo: make this part injectable
 		func(router *mux.Router) {
-			indexerInstance.RegisterRESTRoute(router, tx.RegisterRESTRoute)
-			indexerInstance.RegisterRESTRoute(router, block.RegisterRESTRoute)
+			for _, name := range mantlemintConfig.Indexers {
+				indexerInstance.RegisterRESTRoute(router, indexerServices[name].route)
+			}
+			if resultsVerifier != nil {
+				resultsVerifier.RegisterRESTRoute(router)
+			}
+			if statusFeed, ok := blockFeed.(blockFeeder.StatusBlockFeed); ok {
+				blockFeeder.RegisterStatusRoute(router, statusFeed)
+			}
+			halter.RegisterRESTRoute(router)
 		},
 
 		// inject flag checker for synced
 		blockFeed.IsSynced,
+		halter.IsHalted,
 	)
 
 	if rpcErr != nil {
 		panic(rpcErr)
 	}
 
+	// set when the sync loop halts due to diverged execution results, an unverifiable block, a fork
+	// or an app upgrade
+	var haltErr error
+
+	// block feed is closed early when halting
+	closeBlockFeed := blockFeed.Close
+
 	// start subscribing to block
 	if mantlemintConfig.DisableSync {
 		fmt.Println("running without sync...")
-		forever()
+		<-ctx.Done()
+	} else if halter.Resume(mm.GetCurrentState()) {
+		log.Printf("[sync] height %d is past %s, serving queries only", mm.GetCurrentHeight(), haltCondition)
+		<-ctx.Done()
 	} else if cBlockFeed, blockFeedErr := blockFeed.Subscribe(0); blockFeedErr != nil {
 		panic(blockFeedErr)
 	} else {
 		var rollbackBatch dbm.Batch
+
+		// feeds validating the block stream may halt on forks; nil channel never fires otherwise
+		var cBlockFeedHalted <-chan error
+		if haltingFeed, ok := blockFeed.(blockFeeder.HaltingBlockFeed); ok {
+			cBlockFeedHalted = haltingFeed.Halted()
+		}
+	sync:
 		for {
-			feed := <-cBlockFeed
+			var feed *blockFeeder.BlockResult
+			select {
+			case <-ctx.Done():
+				break sync
+			case haltErr = <-cBlockFeedHalted:
+				break sync
+			case feed = <-cBlockFeed:
+			}
+
+			// finite block feeds close the channel when exhausted; keep serving queries
+			if feed == nil {
+				log.Printf("[sync] block feed exhausted at height %d, serving queries only", mm.GetCurrentHeight())
+				<-ctx.Done()
+				break sync
+			}
 
 			// open db batch
 			hldb.SetWriteHeight(feed.Block.Height)
 			batchedOrigin.Open()
-			if injectErr := mm.Inject(feed.Block); injectErr != nil {
+			var mismatchErr *mantlemint.ResultsHashMismatchError
+			var verificationErr *mantlemint.BlockVerificationError
+			var appUpgradeErr *mantlemint.AppUpgradeError
+			var upgradeNeededErr *mantlemint.UpgradeNeededError
+
+			if injectErr := mm.Inject(feed.Block); errors.As(injectErr, &verificationErr) {
+				// untrusted block was never applied; discard it and stop syncing.
+				// fetching the commit is cut short when shutting down, which isn't a failed verification
+				_ = batchedOrigin.Discard()
+				hldb.ClearWriteHeight()
+				if ctx.Err() == nil {
+					haltErr = verificationErr
+				}
+				break sync
+			} else if errors.As(injectErr, &appUpgradeErr) || errors.As(injectErr, &upgradeNeededErr) {
+				// block is left to the next app; discard it and stop syncing
+				_ = batchedOrigin.Discard()
+				hldb.ClearWriteHeight()
+				haltErr = injectErr
+				break sync
+			} else if errors.As(injectErr, &mismatchErr) {
+				// current block was never applied; discard it and stop syncing
+				_ = batchedOrigin.Discard()
+				hldb.ClearWriteHeight()
+				haltErr = mismatchErr
+
+				if mismatchErr.Policy == mantlemint.ResultsHashPolicyRollback {
+					if revertErr := revertBlock(ldb, rollbackBatch, mantlemintConfig.RollbackJournalSize, mismatchErr.Height); revertErr != nil {
+						log.Printf("[sync] failed to rollback height %d: %v", mismatchErr.Height, revertErr)
+					}
+				}
+				break sync
+			} else if injectErr != nil {
 				// rollback last block
 				if rollbackBatch != nil {
 					fmt.Println("rollback previous block")
@@ -242,32 +323,65 @@ func main() {
 			}
 
 			hldb.ClearWriteHeight()
+			prunerInstance.Commit(feed.Block.Height)
+			tieringInstance.Commit(feed.Block.Height)
 
 			cacheInvalidateChan <- feed.Block.Height
+
+			// frozen for good; stop fetching blocks, keep serving queries
+			if halter.Commit(feed.Block) {
+				log.Printf("[sync] reached %s at height %d, serving queries only", haltCondition, feed.Block.Height)
+				if closeErr := blockFeed.Close(); closeErr != nil {
+					log.Printf("[sync] error closing block feed: %v", closeErr)
+				}
+				closeBlockFeed = func() error { return nil }
+				<-ctx.Done()
+				break sync
+			}
 		}
-	}
-}
 
-// Pass this in as an option to use a dbStoreAdapter instead of an IAVLStore for simulation speed.
-func fauxMerkleModeOpt(app *baseapp.BaseApp) {
-	app.SetFauxMerkleMode()
-}
+		if rollbackBatch != nil {
+			rollbackBatch.Close()
+		}
+	}
 
-func getGenesisDoc(genesisPath string) *tendermint.GenesisDoc {
-	jsonBlob, _ := os.ReadFile(genesisPath)
-	shasum := sha1.New()
-	shasum.Write(jsonBlob)
-	sum := hex.EncodeToString(shasum.Sum(nil))
+	log.Printf("[sync] shutting down at height %d...", mm.GetCurrentHeight())
+	shutdown(
+		closeBlockFeed,
+		apiSrv.Close,
+		indexerInstance.Close,
+		prunerInstance.Close,
+		tieringInstance.Close,
+		appConns.Stop,
+		batched.Close,
+	)
+	log.Printf("[sync] shutdown complete")
 
-	log.Printf("[sync] genesis shasum=%s", sum)
+	if haltErr != nil {
+		log.Printf("[sync] halted due to %v", haltErr)
+		os.Exit(1)
+	}
+}
 
-	if genesis, genesisErr := tendermint.GenesisDocFromFile(genesisPath); genesisErr != nil {
-		panic(genesisErr)
+// revertBlock reverts the block at height, which must be the last flushed block.
+// Rollback journal is preferred as it stays consistent with later `mantlemint rollback` runs.
+func revertBlock(ldb *heleveldb.Driver, rollbackBatch dbm.Batch, journalSize int64, height int64) error {
+	log.Printf("[sync] rolling back height %d", height)
+	if journalSize > 0 {
+		return ldb.RollbackTo(height - 1)
+	} else if rollbackBatch != nil {
+		return rollbackBatch.WriteSync()
 	} else {
-		return genesis
+		return fmt.Errorf("neither rollback journal nor rollback batch is available")
 	}
 }
 
-func forever() {
-	<-(chan int)(nil)
+// shutdown runs all closers in order, logging (but not stopping on) errors
+// so that every resource gets a chance to release its handles.
+func shutdown(closers ...func() error) {
+	for _, closer := range closers {
+		if err := closer(); err != nil {
+			log.Printf("[sync] error during shutdown: %v", err)
+		}
+	}
 }
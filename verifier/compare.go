@@ -0,0 +1,93 @@
+package verifier
+
+import (
+	"fmt"
+	"strconv"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+)
+
+// CompareDeliverTxs compares local results against upstream results field by field.
+// Log and info are not compared, as they are not part of consensus.
+func CompareDeliverTxs(height int64, upstream []abci.ResponseDeliverTx, local []*abci.ResponseDeliverTx) *Report {
+	report := &Report{
+		Height:      height,
+		TxCount:     len(upstream),
+		Divergences: []Divergence{},
+	}
+
+	diverge := func(txIndex int, field string, expected, actual string) {
+		report.Divergences = append(report.Divergences, Divergence{
+			Height:   height,
+			TxIndex:  txIndex,
+			Field:    field,
+			Expected: expected,
+			Actual:   actual,
+		})
+	}
+
+	if len(upstream) != len(local) {
+		diverge(-1, "tx_count", strconv.Itoa(len(upstream)), strconv.Itoa(len(local)))
+		return report
+	}
+
+	for i := range upstream {
+		expected, actual := upstream[i], local[i]
+
+		if expected.Code != actual.Code {
+			diverge(i, "code", fmt.Sprint(expected.Code), fmt.Sprint(actual.Code))
+		}
+		if expected.Codespace != actual.Codespace {
+			diverge(i, "codespace", expected.Codespace, actual.Codespace)
+		}
+		if expected.GasWanted != actual.GasWanted {
+			diverge(i, "gas_wanted", fmt.Sprint(expected.GasWanted), fmt.Sprint(actual.GasWanted))
+		}
+		if expected.GasUsed != actual.GasUsed {
+			diverge(i, "gas_used", fmt.Sprint(expected.GasUsed), fmt.Sprint(actual.GasUsed))
+		}
+		if string(expected.Data) != string(actual.Data) {
+			diverge(i, "data", fmt.Sprintf("%X", expected.Data), fmt.Sprintf("%X", actual.Data))
+		}
+		compareEvents(i, expected.Events, actual.Events, diverge)
+	}
+
+	return report
+}
+
+func compareEvents(txIndex int, expected, actual []abci.Event, diverge func(int, string, string, string)) {
+	if len(expected) != len(actual) {
+		diverge(txIndex, "events", strconv.Itoa(len(expected)), strconv.Itoa(len(actual)))
+		return
+	}
+
+	for i := range expected {
+		field := fmt.Sprintf("events[%d]", i)
+		if expected[i].Type != actual[i].Type {
+			diverge(txIndex, field+".type", expected[i].Type, actual[i].Type)
+			continue
+		}
+
+		if len(expected[i].Attributes) != len(actual[i].Attributes) {
+			diverge(
+				txIndex,
+				field+".attributes",
+				strconv.Itoa(len(expected[i].Attributes)),
+				strconv.Itoa(len(actual[i].Attributes)),
+			)
+			continue
+		}
+
+		for j, attr := range expected[i].Attributes {
+			localAttr := actual[i].Attributes[j]
+			if attr.Key != localAttr.Key || attr.Value != localAttr.Value {
+				diverge(
+					txIndex,
+					fmt.Sprintf("%s.attributes[%d]", field, j),
+					fmt.Sprintf("%s=%s", attr.Key, attr.Value),
+					fmt.Sprintf("%s=%s", localAttr.Key, localAttr.Value),
+				)
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package verifier
+
+import (
+	"testing"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareDeliverTxs(t *testing.T) {
+	upstream := []abci.ResponseDeliverTx{{
+		Code:    0,
+		GasUsed: 100,
+		Events: []abci.Event{{
+			Type:       "transfer",
+			Attributes: []abci.EventAttribute{{Key: "amount", Value: "1uluna"}},
+		}},
+	}}
+
+	same := upstream[0]
+	report := CompareDeliverTxs(1, upstream, []*abci.ResponseDeliverTx{&same})
+	assert.False(t, report.HasDivergence())
+
+	diverged := abci.ResponseDeliverTx{
+		Code:    0,
+		GasUsed: 101,
+		Events: []abci.Event{{
+			Type:       "transfer",
+			Attributes: []abci.EventAttribute{{Key: "amount", Value: "2uluna"}},
+		}},
+	}
+	report = CompareDeliverTxs(1, upstream, []*abci.ResponseDeliverTx{&diverged})
+	assert.Equal(t, []Divergence{
+		{Height: 1, TxIndex: 0, Field: "gas_used", Expected: "100", Actual: "101"},
+		{Height: 1, TxIndex: 0, Field: "events[0].attributes[0]", Expected: "amount=1uluna", Actual: "amount=2uluna"},
+	}, report.Divergences)
+
+	report = CompareDeliverTxs(1, upstream, nil)
+	assert.Equal(t, "tx_count", report.Divergences[0].Field)
+}
@@ -0,0 +1,33 @@
+package verifier
+
+// Divergence is a single field of a DeliverTx result that differs
+// between local execution and the upstream RPC
+type Divergence struct {
+	Height   int64  `json:"height"`
+	TxIndex  int    `json:"tx_index"`
+	Field    string `json:"field"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// Report is the outcome of cross-checking a single block
+type Report struct {
+	Height      int64        `json:"height"`
+	TxCount     int          `json:"tx_count"`
+	Divergences []Divergence `json:"divergences"`
+}
+
+func (r *Report) HasDivergence() bool {
+	return len(r.Divergences) > 0
+}
+
+// Stats summarizes all cross-checks done since startup
+type Stats struct {
+	CheckedBlocks     uint64 `json:"checked_blocks"`
+	CheckedTxs        uint64 `json:"checked_txs"`
+	DivergedBlocks    uint64 `json:"diverged_blocks"`
+	Divergences       uint64 `json:"divergences"`
+	FailedFetches     uint64 `json:"failed_fetches"`
+	LastCheckedBlock  int64  `json:"last_checked_block"`
+	LastDivergedBlock int64  `json:"last_diverged_block"`
+}
@@ -0,0 +1,148 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	tendermint "github.com/cometbft/cometbft/types"
+	"github.com/gorilla/mux"
+	"github.com/terra-money/mantlemint/block_feed"
+	"github.com/terra-money/mantlemint/mantlemint"
+)
+
+var EndpointGETVerifierStats = "/verifier/stats"
+
+// queueSize is how many blocks can wait for cross-check before injection waits for it
+const queueSize = 128
+
+// UpstreamResults returns DeliverTx results of a height as the chain produced them
+type UpstreamResults func(height int64) ([]abci.ResponseDeliverTx, error)
+
+type job struct {
+	height int64
+	local  []*abci.ResponseDeliverTx
+}
+
+// BlockResultsVerifier cross-checks locally collected DeliverTx results against
+// upstream results, e.g. /block_results of an RPC. Checks run in background so that
+// fetching upstream results only slows down block injection once queueSize blocks are waiting.
+type BlockResultsVerifier struct {
+	upstream UpstreamResults
+	queue    chan job
+	mtx      *sync.RWMutex
+	stats    Stats
+
+	// ctx is cancelled by Close, releasing injection waiting for the queue
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewBlockResultsVerifier cross-checks against /block_results of rpcEndpoint
+func NewBlockResultsVerifier(rpcEndpoint string) *BlockResultsVerifier {
+	return NewBlockResultsVerifierFrom(func(height int64) ([]abci.ResponseDeliverTx, error) {
+		return block_feed.FetchBlockResults(rpcEndpoint, height)
+	})
+}
+
+// NewBlockResultsVerifierFrom cross-checks against results from upstream, e.g. those in a block archive
+func NewBlockResultsVerifierFrom(upstream UpstreamResults) *BlockResultsVerifier {
+	ctx, cancel := context.WithCancel(context.Background())
+	v := &BlockResultsVerifier{
+		upstream: upstream,
+		queue:    make(chan job, queueSize),
+		mtx:      new(sync.RWMutex),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+
+	go v.run()
+
+	return v
+}
+
+// RunAfter is to be used as MantlemintCallbackAfter.
+// Every block is checked; once the queue is full, it waits for a check to finish.
+func (v *BlockResultsVerifier) RunAfter(block *tendermint.Block, events *mantlemint.EventCollector) error {
+	select {
+	case v.queue <- job{height: block.Height, local: events.ResponseDeliverTxs}:
+	case <-v.ctx.Done():
+		log.Printf("[verifier] closed, height %d is left unchecked", block.Height)
+	}
+	return nil
+}
+
+// Close stops checking; blocks queued so far are left unchecked
+func (v *BlockResultsVerifier) Close() error {
+	v.cancel()
+	return nil
+}
+
+// Stats returns a snapshot of cross-check statistics
+func (v *BlockResultsVerifier) Stats() Stats {
+	v.mtx.RLock()
+	defer v.mtx.RUnlock()
+	return v.stats
+}
+
+// RegisterRESTRoute exposes Stats under EndpointGETVerifierStats
+func (v *BlockResultsVerifier) RegisterRESTRoute(router *mux.Router) {
+	router.HandleFunc(EndpointGETVerifierStats, func(writer http.ResponseWriter, request *http.Request) {
+		statsJSON, err := json.Marshal(v.Stats())
+		if err != nil {
+			http.Error(writer, err.Error(), 500)
+			return
+		}
+		writer.WriteHeader(200)
+		writer.Write(statsJSON)
+	}).Methods("GET")
+}
+
+// Verify cross-checks a single height synchronously
+func (v *BlockResultsVerifier) Verify(height int64, local []*abci.ResponseDeliverTx) (*Report, error) {
+	upstream, err := v.upstream(height)
+	if err != nil {
+		return nil, err
+	}
+
+	return CompareDeliverTxs(height, upstream, local), nil
+}
+
+func (v *BlockResultsVerifier) run() {
+	for {
+		var j job
+		select {
+		case j = <-v.queue:
+		case <-v.ctx.Done():
+			return
+		}
+
+		report, err := v.Verify(j.height, j.local)
+
+		v.mtx.Lock()
+		if err != nil {
+			v.stats.FailedFetches++
+			v.mtx.Unlock()
+			log.Printf("[verifier] failed to fetch block_results for height %d: %v", j.height, err)
+			continue
+		}
+
+		v.stats.CheckedBlocks++
+		v.stats.CheckedTxs += uint64(report.TxCount)
+		v.stats.LastCheckedBlock = report.Height
+		if report.HasDivergence() {
+			v.stats.DivergedBlocks++
+			v.stats.Divergences += uint64(len(report.Divergences))
+			v.stats.LastDivergedBlock = report.Height
+		}
+		v.mtx.Unlock()
+
+		if report.HasDivergence() {
+			reportJSON, _ := json.Marshal(report)
+			log.Printf("[verifier] divergence detected: %s", reportJSON)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package verifier
+
+import (
+	"testing"
+	"time"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	tendermint "github.com/cometbft/cometbft/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/terra-money/mantlemint/mantlemint"
+)
+
+func TestVerifierBackpressure(t *testing.T) {
+	release := make(chan struct{})
+	v := NewBlockResultsVerifierFrom(func(height int64) ([]abci.ResponseDeliverTx, error) {
+		<-release
+		return nil, nil
+	})
+	defer v.Close()
+
+	// one block being checked, queueSize waiting, and one more that has to wait for room
+	injected := make(chan int64)
+	go func() {
+		for height := int64(1); height <= queueSize+2; height++ {
+			_ = v.RunAfter(&tendermint.Block{Header: tendermint.Header{Height: height}}, &mantlemint.EventCollector{})
+			injected <- height
+		}
+	}()
+	for height := int64(1); height <= queueSize+1; height++ {
+		assert.Equal(t, height, <-injected)
+	}
+	select {
+	case <-injected:
+		t.Fatal("block was queued beyond the queue size")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// every block gets checked once upstream answers
+	close(release)
+	assert.Equal(t, int64(queueSize+2), <-injected)
+	assert.Eventually(t, func() bool {
+		return v.Stats().CheckedBlocks == queueSize+2
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestVerifierClose(t *testing.T) {
+	v := NewBlockResultsVerifierFrom(func(height int64) ([]abci.ResponseDeliverTx, error) {
+		select {}
+	})
+
+	returned := make(chan struct{})
+	go func() {
+		for height := int64(1); height <= queueSize+2; height++ {
+			_ = v.RunAfter(&tendermint.Block{Header: tendermint.Header{Height: height}}, &mantlemint.EventCollector{})
+		}
+		close(returned)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Nil(t, v.Close())
+	select {
+	case <-returned:
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunAfter kept waiting after Close")
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+	"github.com/terra-money/mantlemint/config"
+	"github.com/terra-money/mantlemint/verifier"
+)
+
+// newVerifyCmd handles `mantlemint verify --from <height> --to <height>`.
+// It cross-checks DeliverTx results saved in mantlemint db against /block_results of an RPC,
+// the same way VERIFY_BLOCK_RESULTS does while syncing.
+func newVerifyCmd() *cobra.Command {
+	var fromHeight, toHeight int64
+	var rpcEndpoint string
+
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Cross-check stored execution results against an RPC",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			mantlemintConfig, configErr := config.NewConfig(cmd.Flags())
+			if configErr != nil {
+				return configErr
+			}
+			if rpcEndpoint == "" {
+				rpcEndpoint = mantlemintConfig.VerifyRPCEndpoint
+			}
+			return verify(mantlemintConfig, fromHeight, toHeight, rpcEndpoint)
+		},
+	}
+	cmd.Flags().Int64Var(&fromHeight, "from", 0, "first height to verify")
+	cmd.Flags().Int64Var(&toHeight, "to", 0, "last height to verify; defaults to the current state height")
+	cmd.Flags().StringVar(&rpcEndpoint, "rpc", "", "rpc endpoint to verify against; defaults to $VERIFY_RPC_ENDPOINT")
+	_ = cmd.MarkFlagRequired("from")
+
+	return cmd
+}
+
+func verify(mantlemintConfig config.Config, fromHeight, toHeight int64, rpcEndpoint string) error {
+	view := openStoreView(mantlemintConfig)
+	defer view.Close()
+
+	lastState, stateErr := view.stateStore.Load()
+	if stateErr != nil {
+		return stateErr
+	}
+	stateHeight := lastState.LastBlockHeight
+	if toHeight == 0 || toHeight > stateHeight {
+		toHeight = stateHeight
+	}
+	if fromHeight < 1 || fromHeight > toHeight {
+		return fmt.Errorf("[verify] invalid range %d..%d", fromHeight, toHeight)
+	}
+
+	resultsVerifier := verifier.NewBlockResultsVerifier(rpcEndpoint)
+	divergedBlocks := 0
+	for height := fromHeight; height <= toHeight; height++ {
+		responses, loadErr := view.stateStore.LoadABCIResponses(height)
+		if loadErr != nil {
+			return loadErr
+		}
+
+		report, verifyErr := resultsVerifier.Verify(height, responses.DeliverTxs)
+		if verifyErr != nil {
+			return verifyErr
+		}
+		if report.HasDivergence() {
+			divergedBlocks++
+			reportJSON, _ := json.Marshal(report)
+			fmt.Println(string(reportJSON))
+		}
+		if height%1000 == 0 {
+			log.Printf("[verify] verified up to %d", height)
+		}
+	}
+
+	log.Printf("[verify] verified heights %d..%d, %d diverged", fromHeight, toHeight, divergedBlocks)
+	if divergedBlocks > 0 {
+		return fmt.Errorf("[verify] %d blocks diverged", divergedBlocks)
+	}
+	return nil
+}
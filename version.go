@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/spf13/cobra"
+)
+
+// Version and Commit are set at build time, e.g.
+// -ldflags "-X main.Version=v2.1.2 -X main.Commit=$(git rev-parse HEAD)"
+var (
+	Version = "dev"
+	Commit  = ""
+)
+
+func newVersionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "version",
+		Short: "Print mantlemint version, along with versions of the chain it runs",
+		Args:  cobra.NoArgs,
+		Run: func(_ *cobra.Command, _ []string) {
+			fmt.Printf("mantlemint %s", Version)
+			if Commit != "" {
+				fmt.Printf(" (%s)", Commit)
+			}
+			fmt.Printf("\ngo: %s\n", runtime.Version())
+
+			if buildInfo, ok := debug.ReadBuildInfo(); ok {
+				for _, dep := range buildInfo.Deps {
+					switch dep.Path {
+					case "github.com/classic-terra/core/v3", "github.com/cometbft/cometbft", "github.com/cosmos/cosmos-sdk", "github.com/CosmWasm/wasmd":
+						// forks are pulled in through replace directives
+						if dep.Replace != nil {
+							dep = dep.Replace
+						}
+						fmt.Printf("%s: %s\n", dep.Path, dep.Version)
+					}
+				}
+			}
+		},
+	}
+}